@@ -1,33 +1,119 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
+	"os"
 	"strings"
+	_ "time/tzdata" // account working hours use IANA timezones; the runtime image has no zoneinfo
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/johnson7543/chatsheet-assessment/internal/config"
 	"github.com/johnson7543/chatsheet-assessment/internal/database"
 	"github.com/johnson7543/chatsheet-assessment/internal/handlers"
+	"github.com/johnson7543/chatsheet-assessment/internal/logging"
 	"github.com/johnson7543/chatsheet-assessment/internal/middleware"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
 )
 
 func main() {
+	// Log as JSON at the default level until the configured level is known
+	logging.Setup(config.LogConfig{})
+
 	// Load configuration
 	if err := config.LoadConfig(); err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		slog.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
 	}
 
 	cfg := config.GetConfig()
 
+	// Scrub secrets from every log line from here on
+	logging.Setup(cfg.Log)
+
 	// Initialize database
 	if err := database.InitDatabase(cfg.DatabasePath); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		slog.Error("Failed to initialize database", "error", err)
+		os.Exit(1)
 	}
 
-	// Create Gin router
-	router := gin.Default()
+	// Wire dependencies
+	// Sessions use short-lived access tokens renewed with rotating refresh tokens
+	userRepo := repository.NewUserRepository(database.DB)
+	tokenService := service.NewTokenService(repository.NewRefreshTokenRepository(database.DB), userRepo, cfg.JWTSecret, cfg.JWT)
+	authHandler := handlers.NewAuthHandler(userRepo, tokenService)
+
+	unipileClient := service.NewUnipileService()
+	accountRepo := repository.NewLinkedAccountRepository(database.DB)
+	pendingRepo := repository.NewPendingConnectionRepository(database.DB)
+	// Hosted auth links let users connect LinkedIn on Unipile's own pages
+	hostedAuthNotifyURL := ""
+	if cfg.PublicURL != "" {
+		hostedAuthNotifyURL = strings.TrimRight(cfg.PublicURL, "/") + "/api/linkedin/connect/hosted/notify"
+	}
+	hostedAuth := service.NewHostedAuthService(unipileClient, repository.NewHostedAuthRepository(database.DB), cfg.JWTSecret,
+		cfg.HostedAuth.LinkTTL, hostedAuthNotifyURL, cfg.FrontendURL)
+	linkedInHandler := handlers.NewLinkedInHandler(unipileClient, accountRepo, pendingRepo, hostedAuth)
+	accountsHandler := handlers.NewAccountsHandler(unipileClient, accountRepo)
+
+	// Limit outbound actions per linked account to avoid LinkedIn restrictions
+	quotaService := service.NewQuotaService(repository.NewQuotaRepository(database.DB), cfg.Quota)
+	quotaHandler := handlers.NewQuotaHandler(accountRepo, quotaService)
+
+	// Reuse fetched profiles so repeat lookups don't spend profile views
+	profileService := service.NewProfileService(unipileClient, repository.NewProfileRepository(database.DB), quotaService, cfg.Unipile.ProfileCacheTTL)
+	profilesHandler := handlers.NewProfilesHandler(accountRepo, profileService)
+
+	// Send messages and invitations through a paced, persistent queue
+	invitationRepo := repository.NewInvitationRepository(database.DB)
+	queueRepo := repository.NewQueueRepository(database.DB)
+	actionQueue := service.NewActionQueue(unipileClient, queueRepo, accountRepo, invitationRepo, quotaService, profileService, cfg.Queue)
+	queueHandler := handlers.NewQueueHandler(queueRepo)
+
+	// Personalize messages and notes with templates filled from profiles
+	templateRepo := repository.NewTemplateRepository(database.DB)
+	templatesHandler := handlers.NewTemplatesHandler(templateRepo)
+	messagingHandler := handlers.NewMessagingHandler(unipileClient, accountRepo, templateRepo, profileService, actionQueue)
+	invitationsHandler := handlers.NewInvitationsHandler(unipileClient, accountRepo, invitationRepo, templateRepo, profileService, actionQueue)
+
+	// Import prospects from spreadsheets into lead lists
+	leadsHandler := handlers.NewLeadsHandler(repository.NewLeadRepository(database.DB), accountRepo, templateRepo, actionQueue)
+
+	// Keep a local, searchable copy of every inbox
+	messageRepo := repository.NewMessageRepository(database.DB, database.FTSEnabled())
+	searchHandler := handlers.NewSearchHandler(messageRepo)
+
+	// Run multi-step outreach campaigns on top of the queue
+	campaignRepo := repository.NewCampaignRepository(database.DB)
+	campaignEngine := service.NewCampaignEngine(unipileClient, campaignRepo, accountRepo, invitationRepo, messageRepo,
+		queueRepo, actionQueue, profileService, cfg.Queue.CampaignInterval, cfg.Queue.AcceptanceTimeout)
+	campaignsHandler := handlers.NewCampaignsHandler(campaignRepo, accountRepo, queueRepo)
+
+	// Let users pull their data into spreadsheets
+	exportHandler := handlers.NewExportHandler(repository.NewExportRepository(database.DB))
+
+	// Keep account health statuses and inboxes in sync with Unipile, and work
+	// through the action queue
+	syncCtx, stopSync := context.WithCancel(context.Background())
+	defer stopSync()
+	service.NewAccountSyncer(unipileClient, accountRepo, cfg.Unipile.SyncInterval).Start(syncCtx)
+	service.NewMessageSyncer(unipileClient, accountRepo, messageRepo, cfg.Unipile.MessageSyncInterval).Start(syncCtx)
+	actionQueue.Start(syncCtx)
+	campaignEngine.Start(syncCtx)
+
+	// Process Unipile webhooks in the background
+	webhookRepo := repository.NewWebhookEventRepository(database.DB)
+	webhookProcessor := service.NewWebhookProcessor(webhookRepo, accountRepo, messageRepo, invitationRepo)
+	webhookProcessor.Start(syncCtx)
+	webhookHandler := handlers.NewWebhookHandler(cfg.UnipileWebhookSecret, webhookRepo, webhookProcessor)
+
+	// Create Gin router. Requests are logged as JSON with their request ID
+	// instead of by gin's default logger.
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.Logger(), middleware.Recovery())
 
 	// Configure CORS from config
 	corsConfig := cors.Config{
@@ -45,11 +131,12 @@ func main() {
 				return true
 			}
 			// Log rejected origins to help debug CORS issues
-			log.Printf("CORS: Rejected origin: %s (configured frontend: %s)", origin, cfg.FrontendURL)
+			slog.Warn("CORS: Rejected origin", "origin", origin, "frontend_url", cfg.FrontendURL)
 			return false
 		},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", logging.RequestIDHeader},
+		ExposeHeaders:    []string{logging.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * 3600, // 12 hours
 	}
@@ -69,10 +156,19 @@ func main() {
 		// Authentication routes (public)
 		auth := api.Group("/auth")
 		{
-			auth.POST("/register", handlers.Register)
-			auth.POST("/login", handlers.Login)
+			auth.POST("/register", authHandler.Register)
+			auth.POST("/login", authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", authHandler.Logout)
 		}
 
+		// Webhooks (authenticated by shared secret header)
+		api.POST("/webhooks/unipile", webhookHandler.HandleUnipile)
+
+		// Unipile's hosted auth callback, authenticated by the link's signed
+		// state and notify key
+		api.POST("/linkedin/connect/hosted/notify", linkedInHandler.HostedAuthNotify)
+
 		// Protected routes (require authentication)
 		protected := api.Group("")
 		protected.Use(middleware.AuthMiddleware())
@@ -80,20 +176,86 @@ func main() {
 			// LinkedIn connection routes
 			linkedin := protected.Group("/linkedin")
 			{
-				linkedin.POST("/connect/cookie", handlers.ConnectLinkedInWithCookie)
-				linkedin.POST("/connect/credentials", handlers.ConnectLinkedInWithCredentials)
+				linkedin.POST("/connect/cookie", linkedInHandler.ConnectLinkedInWithCookie)
+				linkedin.POST("/connect/credentials", linkedInHandler.ConnectLinkedInWithCredentials)
+				linkedin.POST("/connect/checkpoint", linkedInHandler.SolveCheckpoint)
+				linkedin.POST("/connect/hosted", linkedInHandler.ConnectLinkedInHosted)
 			}
 
+			// Connection routes for every provider in the registry.
+			// /connect/checkpoint is an alias of /linkedin/connect/checkpoint,
+			// which predates other providers; both solve any provider's checkpoint.
+			protected.GET("/connect/providers", linkedInHandler.ListProviders)
+			protected.POST("/connect/checkpoint", linkedInHandler.SolveCheckpoint)
+			protected.POST("/connect/:provider", linkedInHandler.Connect)
+
 			// Account management routes
-			protected.GET("/accounts", handlers.GetAccounts)
-			protected.DELETE("/accounts/:id", handlers.DeleteAccount)
+			protected.GET("/accounts", accountsHandler.GetAccounts)
+			protected.DELETE("/accounts/:id", accountsHandler.DeleteAccount)
+			protected.PUT("/accounts/:id/reconnect", linkedInHandler.ReconnectAccount)
+			protected.PUT("/accounts/:id/schedule", accountsHandler.UpdateSchedule)
+			protected.GET("/accounts/:id/quota", quotaHandler.GetQuota)
+
+			// Messaging routes
+			protected.GET("/accounts/:id/chats", messagingHandler.ListChats)
+			protected.POST("/accounts/:id/chats", messagingHandler.StartChat)
+			protected.GET("/accounts/:id/chats/:chatId/messages", messagingHandler.ListMessages)
+			protected.POST("/accounts/:id/chats/:chatId/messages", messagingHandler.SendMessage)
+
+			// Invitation routes
+			protected.GET("/accounts/:id/invitations", invitationsHandler.ListInvitations)
+			protected.POST("/accounts/:id/invitations", invitationsHandler.SendInvitation)
+			protected.DELETE("/accounts/:id/invitations/:invitationId", invitationsHandler.WithdrawInvitation)
+
+			// Profile routes
+			protected.GET("/accounts/:id/profiles/:identifier", profilesHandler.GetProfile)
+
+			// Search routes
+			protected.GET("/search/messages", searchHandler.SearchMessages)
+
+			// Action queue routes
+			protected.GET("/queue", queueHandler.ListQueue)
+			protected.DELETE("/queue", queueHandler.CancelQueue)
+			protected.DELETE("/queue/:id", queueHandler.CancelAction)
+
+			// Campaign routes
+			protected.GET("/campaigns", campaignsHandler.ListCampaigns)
+			protected.POST("/campaigns", campaignsHandler.CreateCampaign)
+			protected.GET("/campaigns/:id", campaignsHandler.GetCampaign)
+			protected.PUT("/campaigns/:id", campaignsHandler.UpdateCampaign)
+			protected.DELETE("/campaigns/:id", campaignsHandler.DeleteCampaign)
+			protected.POST("/campaigns/:id/start", campaignsHandler.StartCampaign)
+			protected.POST("/campaigns/:id/pause", campaignsHandler.PauseCampaign)
+			protected.GET("/campaigns/:id/leads", campaignsHandler.ListCampaignLeads)
+			protected.GET("/campaigns/:id/stats", campaignsHandler.GetCampaignStats)
+
+			// Message template routes
+			protected.GET("/templates", templatesHandler.ListTemplates)
+			protected.POST("/templates", templatesHandler.CreateTemplate)
+			protected.GET("/templates/:id", templatesHandler.GetTemplate)
+			protected.PUT("/templates/:id", templatesHandler.UpdateTemplate)
+			protected.DELETE("/templates/:id", templatesHandler.DeleteTemplate)
+			protected.POST("/templates/:id/preview", templatesHandler.PreviewTemplate)
+
+			// Lead routes
+			protected.POST("/leads/import", leadsHandler.ImportLeads)
+			protected.GET("/leads", leadsHandler.ListLeads)
+			protected.DELETE("/leads/:id", leadsHandler.DeleteLead)
+			protected.GET("/lead-lists", leadsHandler.ListLeadLists)
+			protected.GET("/lead-lists/:id", leadsHandler.GetLeadList)
+			protected.DELETE("/lead-lists/:id", leadsHandler.DeleteLeadList)
+			protected.POST("/lead-lists/:id/message", leadsHandler.MessageLeadList)
+
+			// Export routes
+			protected.GET("/export/:dataset", exportHandler.Export)
 		}
 	}
 
 	// Start server
-	log.Printf("Server starting on port %d", cfg.Server.Port)
+	slog.Info("Server starting", "port", cfg.Server.Port)
 
 	if err := router.Run(fmt.Sprintf(":%d", cfg.Server.Port)); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+		slog.Error("Failed to start server", "error", err)
+		os.Exit(1)
 	}
 }
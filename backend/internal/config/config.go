@@ -2,7 +2,7 @@ package config
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
@@ -15,10 +15,22 @@ type Config struct {
 	Server        ServerConfig
 	JWT           JWTConfig
 	Unipile       UnipileConfig
+	Quota         QuotaConfig
+	Queue         QueueConfig
+	HostedAuth    HostedAuthConfig `mapstructure:"hosted_auth"`
+	Log           LogConfig
 	JWTSecret     string
 	UnipileAPIKey string
 	DatabasePath  string
 	FrontendURL   string
+
+	// PublicURL is where Unipile reaches this API, for hosted auth callbacks.
+	// It defaults to localhost only outside production and staging.
+	PublicURL string
+
+	// UnipileWebhookSecret is the value Unipile sends in the Unipile-Auth
+	// header of every webhook; requests without it are rejected
+	UnipileWebhookSecret string
 }
 
 type ServerConfig struct {
@@ -26,7 +38,8 @@ type ServerConfig struct {
 }
 
 type JWTConfig struct {
-	TokenDuration time.Duration `mapstructure:"token_duration"`
+	AccessTokenDuration  time.Duration `mapstructure:"access_token_duration"`
+	RefreshTokenDuration time.Duration `mapstructure:"refresh_token_duration"`
 }
 
 type UnipileConfig struct {
@@ -34,6 +47,51 @@ type UnipileConfig struct {
 	Timeout       time.Duration
 	RetryAttempts int           `mapstructure:"retry_attempts"`
 	RetryDelay    time.Duration `mapstructure:"retry_delay"`
+	SyncInterval  time.Duration `mapstructure:"sync_interval"`
+
+	MessageSyncInterval time.Duration `mapstructure:"message_sync_interval"`
+
+	// ProfileCacheTTL is how long fetched profiles are reused; 0 disables the
+	// cache
+	ProfileCacheTTL time.Duration `mapstructure:"profile_cache_ttl"`
+}
+
+// QuotaConfig limits the outbound actions of each linked account to keep it
+// below LinkedIn's restriction thresholds
+type QuotaConfig struct {
+	Invite      QuotaLimits `mapstructure:"invite"`
+	Message     QuotaLimits `mapstructure:"message"`
+	ProfileView QuotaLimits `mapstructure:"profile_view"`
+}
+
+// QuotaLimits are rolling 24 hour and 7 day limits; 0 means unlimited
+type QuotaLimits struct {
+	Daily  int `mapstructure:"daily"`
+	Weekly int `mapstructure:"weekly"`
+}
+
+// QueueConfig controls the outbound action queue
+type QueueConfig struct {
+	Workers      int
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	MinDelay     time.Duration `mapstructure:"min_delay"`
+	MaxDelay     time.Duration `mapstructure:"max_delay"`
+	MaxAttempts  int           `mapstructure:"max_attempts"`
+
+	CampaignInterval  time.Duration `mapstructure:"campaign_interval"`
+	AcceptanceTimeout time.Duration `mapstructure:"acceptance_timeout"`
+}
+
+// HostedAuthConfig controls Unipile's hosted auth wizard links
+type HostedAuthConfig struct {
+	LinkTTL time.Duration `mapstructure:"link_ttl"`
+}
+
+// LogConfig controls application logging
+type LogConfig struct {
+	// Level is debug, info, warn or error. Request and response bodies are
+	// only logged at debug.
+	Level string
 }
 
 var App *Config
@@ -58,7 +116,7 @@ func LoadConfig() error {
 		return fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	log.Printf("Loaded config from: %s", viper.ConfigFileUsed())
+	slog.Info("Loaded config", "file", viper.ConfigFileUsed())
 
 	// Check for environment-specific config
 	env := getEnv("APP_ENV", viper.GetString("app.environment"))
@@ -66,9 +124,9 @@ func LoadConfig() error {
 	if env == "production" || env == "staging" {
 		viper.SetConfigName("config." + env)
 		if err := viper.MergeInConfig(); err != nil {
-			log.Printf("No %s config found, using defaults", env)
+			slog.Info("No environment config found, using defaults", "env", env)
 		} else {
-			log.Printf("Loaded %s overrides", env)
+			slog.Info("Loaded environment overrides", "env", env)
 		}
 	}
 
@@ -81,13 +139,18 @@ func LoadConfig() error {
 	// Load secrets from environment variables
 	cfg.JWTSecret = getEnv("JWT_SECRET", "")
 	if cfg.JWTSecret == "" {
-		log.Println("WARNING: JWT_SECRET not set! Using insecure default.")
+		slog.Warn("JWT_SECRET not set! Using insecure default.")
 		cfg.JWTSecret = "default-secret-change-in-production"
 	}
 
 	cfg.UnipileAPIKey = getEnv("UNIPILE_API_KEY", "")
 	if cfg.UnipileAPIKey == "" {
-		log.Println("WARNING: UNIPILE_API_KEY not set!")
+		slog.Warn("UNIPILE_API_KEY not set!")
+	}
+
+	cfg.UnipileWebhookSecret = getEnv("UNIPILE_WEBHOOK_SECRET", "")
+	if cfg.UnipileWebhookSecret == "" {
+		slog.Warn("UNIPILE_WEBHOOK_SECRET not set! Unipile webhooks will be rejected.")
 	}
 
 	cfg.DatabasePath = getEnv("DATABASE_PATH", "./linkedin_connector.db")
@@ -104,6 +167,15 @@ func LoadConfig() error {
 		}
 	}
 
+	cfg.PublicURL = getEnv("PUBLIC_URL", "")
+	if cfg.PublicURL == "" {
+		if env == "production" || env == "staging" {
+			slog.Warn("PUBLIC_URL not set! Hosted auth links are disabled.")
+		} else {
+			cfg.PublicURL = fmt.Sprintf("http://localhost:%d", cfg.Server.Port)
+		}
+	}
+
 	// Assign to global App variable
 	App = cfg
 
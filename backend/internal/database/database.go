@@ -1,7 +1,7 @@
 package database
 
 import (
-	"log"
+	"log/slog"
 
 	"github.com/johnson7543/chatsheet-assessment/internal/models"
 	"gorm.io/driver/sqlite"
@@ -11,38 +11,136 @@ import (
 
 var DB *gorm.DB
 
+// ftsEnabled reports whether the SQLite build supports FTS5. Binaries built
+// without the sqlite_fts5 tag fall back to LIKE-based message search.
+var ftsEnabled bool
+
 // InitDatabase initializes the database connection and runs migrations
 func InitDatabase(dbPath string) error {
 	var err error
 
 	// Open database connection
 	DB, err = gorm.Open(sqlite.Open(dbPath), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger:         queryLogger{level: logger.Info},
+		TranslateError: true,
 	})
 	if err != nil {
 		return err
 	}
 
-	log.Println("Database connection established")
+	slog.Info("Database connection established")
 
 	// Run migrations
 	if err := RunMigrations(); err != nil {
 		return err
 	}
 
-	log.Println("Database migrations completed")
+	slog.Info("Database migrations completed")
+
+	if err := createMessageSearchIndex(); err != nil {
+		slog.Warn("Full-text search unavailable, falling back to LIKE search", "error", err)
+	} else {
+		ftsEnabled = true
+	}
+
 	return nil
 }
 
 // RunMigrations runs all database migrations
 func RunMigrations() error {
+	if err := dedupeLinkedAccounts(); err != nil {
+		return err
+	}
+
 	return DB.AutoMigrate(
 		&models.User{},
 		&models.LinkedAccount{},
+		&models.RefreshToken{},
+		&models.PendingConnection{},
+		&models.HostedAuthSession{},
+		&models.WebhookEvent{},
+		&models.SyncedChat{},
+		&models.SyncedMessage{},
+		&models.MessageSyncState{},
+		&models.Invitation{},
+		&models.QuotaUsage{},
+		&models.QueuedAction{},
+		&models.Campaign{},
+		&models.CampaignLead{},
+		&models.MessageTemplate{},
+		&models.Lead{},
+		&models.LeadList{},
+		&models.LeadListEntry{},
+		&models.CachedProfile{},
 	)
 }
 
+// createMessageSearchIndex creates the FTS5 index over synced message text,
+// kept in sync with synced_messages by triggers. Existing rows are indexed
+// the first time the table is created.
+func createMessageSearchIndex() error {
+	var exists int64
+	if err := DB.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'synced_messages_fts'").Scan(&exists).Error; err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS synced_messages_fts USING fts5(
+			text, content='synced_messages', content_rowid='id', tokenize='unicode61 remove_diacritics 2'
+		)`,
+		`CREATE TRIGGER IF NOT EXISTS synced_messages_fts_ai AFTER INSERT ON synced_messages BEGIN
+			INSERT INTO synced_messages_fts(rowid, text) VALUES (new.id, new.text);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS synced_messages_fts_ad AFTER DELETE ON synced_messages BEGIN
+			INSERT INTO synced_messages_fts(synced_messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS synced_messages_fts_au AFTER UPDATE OF text ON synced_messages BEGIN
+			INSERT INTO synced_messages_fts(synced_messages_fts, rowid, text) VALUES ('delete', old.id, old.text);
+			INSERT INTO synced_messages_fts(rowid, text) VALUES (new.id, new.text);
+		END`,
+	}
+	if exists == 0 {
+		statements = append(statements, `INSERT INTO synced_messages_fts(synced_messages_fts) VALUES ('rebuild')`)
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range statements {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// FTSEnabled reports whether full-text message search is available
+func FTSEnabled() bool {
+	return ftsEnabled
+}
+
 // GetDB returns the database instance
 func GetDB() *gorm.DB {
 	return DB
 }
+
+// dedupeLinkedAccounts soft deletes duplicate active linked accounts, keeping
+// the newest, so the unique (user_id, provider, account_id) index can be built
+// on databases created before it existed
+func dedupeLinkedAccounts() error {
+	if !DB.Migrator().HasTable(&models.LinkedAccount{}) {
+		return nil
+	}
+
+	result := DB.Exec(`UPDATE linked_accounts SET deleted_at = CURRENT_TIMESTAMP
+		WHERE deleted_at IS NULL AND id NOT IN (
+			SELECT MAX(id) FROM linked_accounts WHERE deleted_at IS NULL
+			GROUP BY user_id, provider, account_id
+		)`)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		slog.Info("Removed duplicate linked accounts", "count", result.RowsAffected)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold is how long a query may take before it's logged as slow
+const slowQueryThreshold = 200 * time.Millisecond
+
+// queryLogger writes GORM's logs through slog, so queries run with a request's
+// context carry its request ID. Queries are logged without their values, which
+// include password hashes.
+type queryLogger struct {
+	level logger.LogLevel
+}
+
+func (l queryLogger) LogMode(level logger.LogLevel) logger.Interface {
+	l.level = level
+	return l
+}
+
+func (l queryLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		slog.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l queryLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		slog.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l queryLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		slog.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l queryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	sql, rows := fc()
+	attrs := []any{"sql", sql, "rows", rows, "duration_ms", float64(elapsed.Microseconds()) / 1000}
+
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound) && l.level >= logger.Error:
+		slog.ErrorContext(ctx, "Query failed", append(attrs, "error", err)...)
+	case elapsed > slowQueryThreshold && l.level >= logger.Warn:
+		slog.WarnContext(ctx, "Slow query", attrs...)
+	case l.level >= logger.Info:
+		slog.InfoContext(ctx, "Query", attrs...)
+	}
+}
+
+// ParamsFilter leaves values out of logged queries
+func (l queryLogger) ParamsFilter(_ context.Context, sql string, _ ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
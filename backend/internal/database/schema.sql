@@ -15,16 +15,46 @@ CREATE TABLE IF NOT EXISTS users (
 CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at);
 
+-- Refresh Tokens table (rotated on every refresh; a reused token revokes its family)
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    family_id TEXT NOT NULL, -- shared by the tokens rotated from one login
+    token_hash TEXT NOT NULL UNIQUE, -- SHA-256 of the token; the token itself is never stored
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME, -- set when the token is rotated
+    revoked_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+
 -- Linked Accounts table
 CREATE TABLE IF NOT EXISTS linked_accounts (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
     user_id INTEGER NOT NULL,
-    provider TEXT NOT NULL DEFAULT 'linkedin',
+    provider TEXT NOT NULL DEFAULT 'linkedin', -- linkedin, whatsapp, instagram, telegram, gmail, outlook
     account_id TEXT NOT NULL,
     account_name TEXT,
+    status TEXT NOT NULL DEFAULT 'OK', -- OK, CREDENTIALS, DISCONNECTED, CONNECTING, ERROR, PENDING_DELETION
+    last_checked_at DATETIME,
+    last_error TEXT,
     created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     deleted_at DATETIME,
+    timezone TEXT NOT NULL DEFAULT 'UTC', -- IANA timezone of the working hours
+    working_hours_start TEXT NOT NULL DEFAULT '09:00',
+    working_hours_end TEXT NOT NULL DEFAULT '18:00',
+    working_days TEXT NOT NULL DEFAULT 'Mon,Tue,Wed,Thu,Fri',
+    public_identifier TEXT, -- the owner's own LinkedIn profile, from Unipile's GET /users/me
+    profile_url TEXT,
+    headline TEXT,
+    avatar_url TEXT,
+    premium BOOLEAN NOT NULL DEFAULT 0,
+    sales_navigator BOOLEAN NOT NULL DEFAULT 0,
+    profile_synced_at DATETIME,
     FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 
@@ -32,6 +62,294 @@ CREATE TABLE IF NOT EXISTS linked_accounts (
 CREATE INDEX IF NOT EXISTS idx_linked_accounts_user_id ON linked_accounts(user_id);
 CREATE INDEX IF NOT EXISTS idx_linked_accounts_deleted_at ON linked_accounts(deleted_at);
 CREATE INDEX IF NOT EXISTS idx_linked_accounts_provider ON linked_accounts(provider);
+CREATE INDEX IF NOT EXISTS idx_linked_accounts_status ON linked_accounts(status);
+-- A user can link each provider account only once (soft-deleted rows excluded)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_linked_accounts_user_provider_account
+    ON linked_accounts(user_id, provider, account_id) WHERE deleted_at IS NULL;
+
+-- Pending Connections table (LinkedIn checkpoints awaiting a code or in-app validation)
+CREATE TABLE IF NOT EXISTS pending_connections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL UNIQUE,
+    linked_account_id INTEGER,
+    provider TEXT NOT NULL DEFAULT 'linkedin',
+    account_id TEXT NOT NULL,
+    checkpoint_type TEXT,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Hosted auth sessions table (Unipile hosted auth links; each connects one account)
+CREATE TABLE IF NOT EXISTS hosted_auth_sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    nonce TEXT NOT NULL UNIQUE, -- part of the link's signed state
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME, -- set by the notify callback; used links are rejected
+    account_id TEXT, -- Unipile account connected through the link
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_hosted_auth_sessions_user_id ON hosted_auth_sessions(user_id);
+
+-- Webhook Events table (Unipile webhooks, deduplicated on event_id)
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id TEXT NOT NULL UNIQUE,
+    type TEXT NOT NULL,
+    account_id TEXT,
+    linked_account_id INTEGER,
+    payload TEXT NOT NULL,
+    processed_at DATETIME,
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0, -- failed processing attempts; retried until max
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_events_type ON webhook_events(type);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_account_id ON webhook_events(account_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_events_processed_at ON webhook_events(processed_at);
+
+-- Synced Chats table (local copy of Unipile chats)
+CREATE TABLE IF NOT EXISTS synced_chats (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    linked_account_id INTEGER NOT NULL,
+    chat_id TEXT NOT NULL,
+    provider_id TEXT,
+    attendee_provider_id TEXT,
+    name TEXT,
+    type TEXT,
+    unread_count INTEGER NOT NULL DEFAULT 0,
+    last_activity_at DATETIME,
+    last_synced_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    backfill_cursor TEXT, -- where copying older messages continues; empty once done
+    FOREIGN KEY (linked_account_id) REFERENCES linked_accounts(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_synced_chats_account_chat ON synced_chats(linked_account_id, chat_id);
+
+-- Message Sync States table (where each account's chat backfill continues)
+CREATE TABLE IF NOT EXISTS message_sync_states (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    linked_account_id INTEGER NOT NULL,
+    chat_cursor TEXT, -- Unipile cursor of the chats still to be copied; empty once done
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (linked_account_id) REFERENCES linked_accounts(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_message_sync_states_linked_account_id ON message_sync_states(linked_account_id);
+
+-- Synced Messages table (local copy of Unipile messages)
+CREATE TABLE IF NOT EXISTS synced_messages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    linked_account_id INTEGER NOT NULL,
+    message_id TEXT NOT NULL,
+    chat_id TEXT NOT NULL,
+    provider_id TEXT,
+    sender_id TEXT,
+    sender_name TEXT,
+    is_me NUMERIC NOT NULL DEFAULT 0,
+    text TEXT,
+    sent_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (linked_account_id) REFERENCES linked_accounts(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_synced_messages_account_message ON synced_messages(linked_account_id, message_id);
+CREATE INDEX IF NOT EXISTS idx_synced_messages_chat_id ON synced_messages(chat_id);
+CREATE INDEX IF NOT EXISTS idx_synced_messages_sent_at ON synced_messages(sent_at);
+
+-- Full-text index over message text (requires building with -tags sqlite_fts5)
+CREATE VIRTUAL TABLE IF NOT EXISTS synced_messages_fts USING fts5(
+    text, content='synced_messages', content_rowid='id', tokenize='unicode61 remove_diacritics 2'
+);
+-- Triggers synced_messages_fts_ai/_ad/_au keep the index up to date (see database.go)
+
+-- Invitations table (LinkedIn connection requests sent from linked accounts)
+CREATE TABLE IF NOT EXISTS invitations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    linked_account_id INTEGER NOT NULL,
+    invitation_id TEXT,
+    recipient_provider_id TEXT NOT NULL,
+    recipient_identifier TEXT,
+    recipient_name TEXT,
+    note TEXT,
+    status TEXT NOT NULL DEFAULT 'PENDING', -- PENDING, ACCEPTED, WITHDRAWN
+    sent_at DATETIME NOT NULL,
+    accepted_at DATETIME,
+    withdrawn_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (linked_account_id) REFERENCES linked_accounts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_invitations_linked_account_id ON invitations(linked_account_id);
+CREATE INDEX IF NOT EXISTS idx_invitations_invitation_id ON invitations(invitation_id);
+CREATE INDEX IF NOT EXISTS idx_invitations_recipient_provider_id ON invitations(recipient_provider_id);
+CREATE INDEX IF NOT EXISTS idx_invitations_status ON invitations(status);
+
+-- Quota usages table (outbound actions counted against per-account quotas)
+CREATE TABLE IF NOT EXISTS quota_usages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    linked_account_id INTEGER NOT NULL,
+    action TEXT NOT NULL, -- invite, message, profile_view
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (linked_account_id) REFERENCES linked_accounts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_quota_usages_account_action ON quota_usages(linked_account_id, action);
+CREATE INDEX IF NOT EXISTS idx_quota_usages_created_at ON quota_usages(created_at);
+
+-- Queued actions table (outbound messages and invitations sent by the action queue)
+CREATE TABLE IF NOT EXISTS queued_actions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    linked_account_id INTEGER NOT NULL,
+    type TEXT NOT NULL, -- send_message, start_chat, send_invitation
+    target TEXT NOT NULL, -- chat ID or recipient provider ID
+    payload TEXT, -- JSON: text, note, recipient details
+    status TEXT NOT NULL DEFAULT 'PENDING', -- PENDING, RUNNING, DONE, FAILED, CANCELLED
+    scheduled_at DATETIME NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    result_id TEXT,
+    started_at DATETIME,
+    completed_at DATETIME,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (linked_account_id) REFERENCES linked_accounts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_queued_actions_user_id ON queued_actions(user_id);
+CREATE INDEX IF NOT EXISTS idx_queued_actions_linked_account_id ON queued_actions(linked_account_id);
+CREATE INDEX IF NOT EXISTS idx_queued_actions_target ON queued_actions(target);
+CREATE INDEX IF NOT EXISTS idx_queued_actions_status_scheduled ON queued_actions(status, scheduled_at);
+
+-- Campaigns table (multi-step outreach sequences)
+CREATE TABLE IF NOT EXISTS campaigns (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    linked_account_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'DRAFT', -- DRAFT, ACTIVE, PAUSED, COMPLETED
+    steps TEXT, -- JSON array of {type: invite|wait|message, days, text}
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (linked_account_id) REFERENCES linked_accounts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_user_id ON campaigns(user_id);
+CREATE INDEX IF NOT EXISTS idx_campaigns_linked_account_id ON campaigns(linked_account_id);
+CREATE INDEX IF NOT EXISTS idx_campaigns_status ON campaigns(status);
+
+-- Campaign leads table (each lead's progress through a campaign)
+CREATE TABLE IF NOT EXISTS campaign_leads (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    campaign_id INTEGER NOT NULL,
+    identifier TEXT NOT NULL, -- public identifier from the profile URL
+    profile_url TEXT,
+    provider_id TEXT,
+    name TEXT,
+    status TEXT NOT NULL DEFAULT 'ACTIVE', -- ACTIVE, REPLIED, COMPLETED, NOT_ACCEPTED, FAILED
+    current_step INTEGER NOT NULL DEFAULT 0,
+    next_action_at DATETIME,
+    queued_action_id INTEGER,
+    chat_id TEXT,
+    started_at DATETIME,
+    invited_at DATETIME,
+    accepted_at DATETIME,
+    messages_sent INTEGER NOT NULL DEFAULT 0,
+    last_message_at DATETIME,
+    replied_at DATETIME,
+    last_error TEXT,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (campaign_id) REFERENCES campaigns(id) ON DELETE CASCADE,
+    FOREIGN KEY (queued_action_id) REFERENCES queued_actions(id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_campaign_leads_campaign_identifier ON campaign_leads(campaign_id, identifier);
+CREATE INDEX IF NOT EXISTS idx_campaign_leads_status ON campaign_leads(status);
+
+-- Message templates table (text/template bodies filled from recipient profiles)
+CREATE TABLE IF NOT EXISTS message_templates (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    body TEXT NOT NULL,
+    variables TEXT, -- JSON array of the placeholders used in body
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_templates_user_id ON message_templates(user_id);
+
+-- Leads table (prospects imported from CSV, unique per user by profile)
+CREATE TABLE IF NOT EXISTS leads (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    public_identifier TEXT NOT NULL, -- lowercased, from the profile URL
+    profile_url TEXT NOT NULL,
+    first_name TEXT,
+    last_name TEXT,
+    company TEXT,
+    custom_fields TEXT, -- JSON object of custom field values
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_leads_user_identifier ON leads(user_id, public_identifier);
+
+-- Lead lists table (named groups of leads)
+CREATE TABLE IF NOT EXISTS lead_lists (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_lead_lists_user_name ON lead_lists(user_id, name);
+
+-- Lead list entries table (which leads are in which lists)
+CREATE TABLE IF NOT EXISTS lead_list_entries (
+    lead_list_id INTEGER NOT NULL,
+    lead_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (lead_list_id, lead_id),
+    FOREIGN KEY (lead_list_id) REFERENCES lead_lists(id) ON DELETE CASCADE,
+    FOREIGN KEY (lead_id) REFERENCES leads(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_lead_list_entries_lead_id ON lead_list_entries(lead_id);
+
+-- Cached profiles table (LinkedIn profiles fetched through each account)
+CREATE TABLE IF NOT EXISTS cached_profiles (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    linked_account_id INTEGER NOT NULL,
+    provider_id TEXT NOT NULL,
+    public_identifier TEXT, -- lowercased
+    profile TEXT, -- JSON of the normalized profile
+    fetched_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (linked_account_id) REFERENCES linked_accounts(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cached_profiles_account_provider ON cached_profiles(linked_account_id, provider_id);
+CREATE INDEX IF NOT EXISTS idx_cached_profiles_public_identifier ON cached_profiles(public_identifier);
 
 -- Example queries:
 
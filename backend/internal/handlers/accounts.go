@@ -1,19 +1,37 @@
 package handlers
 
 import (
+	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/johnson7543/chatsheet-assessment/internal/database"
 	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
 )
 
+// AccountsHandler handles linked account management requests
+type AccountsHandler struct {
+	unipile  service.UnipileClient
+	accounts *repository.LinkedAccountRepository
+}
+
+// NewAccountsHandler creates a new accounts handler
+func NewAccountsHandler(unipile service.UnipileClient, accounts *repository.LinkedAccountRepository) *AccountsHandler {
+	return &AccountsHandler{
+		unipile:  unipile,
+		accounts: accounts,
+	}
+}
+
 // GetAccounts retrieves all linked accounts for the authenticated user
-func GetAccounts(c *gin.Context) {
+func (h *AccountsHandler) GetAccounts(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
-	var accounts []models.LinkedAccount
-	if err := database.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&accounts).Error; err != nil {
+	accounts, err := h.accounts.WithContext(c.Request.Context()).FindByUserID(userID)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch accounts"})
 		return
 	}
@@ -24,21 +42,89 @@ func GetAccounts(c *gin.Context) {
 	})
 }
 
-// DeleteAccount removes a linked account
-func DeleteAccount(c *gin.Context) {
-	userID := c.GetUint("user_id")
-	accountID := c.Param("id")
-
-	var account models.LinkedAccount
-	if err := database.DB.Where("id = ? AND user_id = ?", accountID, userID).First(&account).Error; err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Account not found"})
+// DeleteAccount removes a linked account. The account is also deleted from
+// Unipile unless keep_remote=true is passed. If the Unipile call fails the
+// account is marked PENDING_DELETION, the deletion is retried in the
+// background, and 202 is returned.
+func (h *AccountsHandler) DeleteAccount(c *gin.Context) {
+	account, ok := findOwnedAccount(c, h.accounts)
+	if !ok {
 		return
 	}
 
-	if err := database.DB.Delete(&account).Error; err != nil {
+	keepRemote, _ := strconv.ParseBool(c.Query("keep_remote"))
+	if !keepRemote {
+		if err := h.unipile.DeleteAccount(c.Request.Context(), account.AccountID); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Failed to delete account from Unipile", "account_id", account.ID, "error", err)
+
+			if err := h.accounts.WithContext(c.Request.Context()).UpdateStatus(account.ID, models.AccountStatusPendingDeletion, err.Error(), time.Now()); err != nil {
+				c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete account"})
+				return
+			}
+
+			c.JSON(http.StatusAccepted, gin.H{"message": "Account scheduled for deletion"})
+			return
+		}
+	}
+
+	if err := h.accounts.WithContext(c.Request.Context()).Delete(account); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete account"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Account deleted successfully"})
 }
+
+// UpdateSchedule sets the timezone, working hours and working days within
+// which queued actions of the account are sent
+func (h *AccountsHandler) UpdateSchedule(c *gin.Context) {
+	account, ok := findOwnedAccount(c, h.accounts)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	hours, err := service.ParseWorkingHours(req.Timezone, req.WorkingHoursStart, req.WorkingHoursEnd, req.WorkingDays)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	account.Timezone = hours.Location.String()
+	account.WorkingHoursStart = hours.StartClock()
+	account.WorkingHoursEnd = hours.EndClock()
+	account.WorkingDays = hours.FormatDays()
+	if err := h.accounts.WithContext(c.Request.Context()).Update(account); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update schedule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Schedule updated",
+		"account": account,
+	})
+}
+
+// findOwnedAccount loads the linked account named by the :id path parameter,
+// checking it belongs to the authenticated user. On failure it writes the
+// error response and returns false.
+func findOwnedAccount(c *gin.Context, accounts *repository.LinkedAccountRepository) (*models.LinkedAccount, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid account ID"})
+		return nil, false
+	}
+
+	account, err := accounts.WithContext(c.Request.Context()).FindByUserIDAndID(c.GetUint("user_id"), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Account not found"})
+		return nil, false
+	}
+
+	return account, true
+}
@@ -1,19 +1,33 @@
 package handlers
 
 import (
+	"errors"
+	"log/slog"
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
-	"github.com/johnson7543/chatsheet-assessment/internal/config"
-	"github.com/johnson7543/chatsheet-assessment/internal/database"
 	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// AuthHandler handles registration, login and the token lifecycle
+type AuthHandler struct {
+	users  *repository.UserRepository
+	tokens *service.TokenService
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(users *repository.UserRepository, tokens *service.TokenService) *AuthHandler {
+	return &AuthHandler{
+		users:  users,
+		tokens: tokens,
+	}
+}
+
 // Register handles user registration
-func Register(c *gin.Context) {
+func (h *AuthHandler) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
@@ -21,8 +35,8 @@ func Register(c *gin.Context) {
 	}
 
 	// Check if user already exists
-	var existingUser models.User
-	if err := database.DB.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
+	users := h.users.WithContext(c.Request.Context())
+	if _, err := users.FindByEmail(req.Email); err == nil {
 		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "User already exists"})
 		return
 	}
@@ -40,29 +54,16 @@ func Register(c *gin.Context) {
 		Password: string(hashedPassword),
 	}
 
-	if err := database.DB.Create(&user).Error; err != nil {
+	if err := users.Create(&user); err != nil {
 		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create user"})
 		return
 	}
 
-	// Generate JWT token
-	token, err := generateToken(user.ID, user.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate token"})
-		return
-	}
-
-	// Prepare response
-	var response models.AuthResponse
-	response.Token = token
-	response.User.ID = user.ID
-	response.User.Email = user.Email
-
-	c.JSON(http.StatusCreated, response)
+	h.issueTokens(c, http.StatusCreated, &user)
 }
 
 // Login handles user authentication
-func Login(c *gin.Context) {
+func (h *AuthHandler) Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
@@ -70,8 +71,8 @@ func Login(c *gin.Context) {
 	}
 
 	// Find user by email
-	var user models.User
-	if err := database.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+	user, err := h.users.WithContext(c.Request.Context()).FindByEmail(req.Email)
+	if err != nil {
 		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid email or password"})
 		return
 	}
@@ -82,30 +83,71 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := generateToken(user.ID, user.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate token"})
+	h.issueTokens(c, http.StatusOK, user)
+}
+
+// Refresh exchanges a refresh token for a new access token and a new refresh
+// token. Each refresh token works once; replaying one logs its session out.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	// Prepare response
-	var response models.AuthResponse
-	response.Token = token
-	response.User.ID = user.ID
-	response.User.Email = user.Email
+	pair, user, err := h.tokens.Refresh(c.Request.Context(), req.RefreshToken)
+	switch {
+	case errors.Is(err, service.ErrRefreshTokenReused):
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Refresh token already used; please log in again"})
+		return
+	case errors.Is(err, service.ErrInvalidRefreshToken):
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid or expired refresh token"})
+		return
+	case err != nil:
+		slog.ErrorContext(c.Request.Context(), "Failed to refresh tokens", "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to refresh token"})
+		return
+	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, authResponse(user, pair))
 }
 
-// generateToken creates a JWT token for the user
-func generateToken(userID uint, email string) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"email":   email,
-		"exp":     time.Now().Add(config.App.JWT.TokenDuration).Unix(),
+// Logout revokes a refresh token and every token rotated from the same login.
+// Access tokens already issued stay valid until they expire.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if err := h.tokens.Revoke(c.Request.Context(), req.RefreshToken); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to revoke refresh token", "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to log out"})
+		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.App.JWTSecret))
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// issueTokens starts a new session for the user and writes its tokens
+func (h *AuthHandler) issueTokens(c *gin.Context, status int, user *models.User) {
+	pair, err := h.tokens.Issue(c.Request.Context(), user)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to issue tokens", "user_id", user.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to generate token"})
+		return
+	}
+
+	c.JSON(status, authResponse(user, pair))
+}
+
+func authResponse(user *models.User, pair *service.TokenPair) models.AuthResponse {
+	var response models.AuthResponse
+	response.Token = pair.AccessToken
+	response.ExpiresAt = pair.ExpiresAt
+	response.RefreshToken = pair.RefreshToken
+	response.User.ID = user.ID
+	response.User.Email = user.Email
+	return response
 }
@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
+)
+
+// CampaignsHandler handles outreach campaign requests
+type CampaignsHandler struct {
+	campaigns *repository.CampaignRepository
+	accounts  *repository.LinkedAccountRepository
+	actions   *repository.QueueRepository
+}
+
+// NewCampaignsHandler creates a new campaigns handler
+func NewCampaignsHandler(campaigns *repository.CampaignRepository, accounts *repository.LinkedAccountRepository, actions *repository.QueueRepository) *CampaignsHandler {
+	return &CampaignsHandler{
+		campaigns: campaigns,
+		accounts:  accounts,
+		actions:   actions,
+	}
+}
+
+// CreateCampaign creates a draft campaign with its steps and leads
+func (h *CampaignsHandler) CreateCampaign(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	var req models.CreateCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if err := service.ValidateCampaignSteps(req.Steps); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if _, err := h.accounts.WithContext(c.Request.Context()).FindByUserIDAndID(userID, req.AccountID); err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Account not found"})
+		return
+	}
+
+	leads, invalid := service.NewCampaignLeads(0, req.ProfileURLs)
+	if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":        "Some entries are not LinkedIn profile URLs",
+			"invalid_urls": invalid,
+		})
+		return
+	}
+
+	campaign := &models.Campaign{
+		UserID:          userID,
+		LinkedAccountID: req.AccountID,
+		Name:            strings.TrimSpace(req.Name),
+		Status:          models.CampaignStatusDraft,
+		Steps:           req.Steps,
+	}
+	if err := h.campaigns.WithContext(c.Request.Context()).Create(campaign); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create campaign"})
+		return
+	}
+
+	for i := range leads {
+		leads[i].CampaignID = campaign.ID
+	}
+	if _, err := h.campaigns.WithContext(c.Request.Context()).AddLeads(leads); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to add leads to campaign", "campaign_id", campaign.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to add leads"})
+		return
+	}
+
+	h.respond(c, http.StatusCreated, campaign)
+}
+
+// ListCampaigns lists the user's campaigns with their stats
+func (h *CampaignsHandler) ListCampaigns(c *gin.Context) {
+	campaigns, err := h.campaigns.WithContext(c.Request.Context()).FindByUserID(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch campaigns"})
+		return
+	}
+
+	responses := make([]models.CampaignResponse, 0, len(campaigns))
+	for _, campaign := range campaigns {
+		stats, err := h.campaigns.WithContext(c.Request.Context()).Stats(campaign.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch campaign stats"})
+			return
+		}
+		responses = append(responses, models.CampaignResponse{Campaign: campaign, Stats: stats})
+	}
+
+	c.JSON(http.StatusOK, models.CampaignListResponse{
+		Campaigns: responses,
+		Count:     len(responses),
+	})
+}
+
+// GetCampaign returns a campaign with its stats
+func (h *CampaignsHandler) GetCampaign(c *gin.Context) {
+	campaign, ok := h.findOwnedCampaign(c)
+	if !ok {
+		return
+	}
+	h.respond(c, http.StatusOK, campaign)
+}
+
+// GetCampaignStats returns the stats of a campaign
+func (h *CampaignsHandler) GetCampaignStats(c *gin.Context) {
+	campaign, ok := h.findOwnedCampaign(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.campaigns.WithContext(c.Request.Context()).Stats(campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch campaign stats"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// UpdateCampaign renames a campaign, replaces its steps and adds leads. Steps
+// can't change while the campaign is active; leads keep their step position.
+func (h *CampaignsHandler) UpdateCampaign(c *gin.Context) {
+	campaign, ok := h.findOwnedCampaign(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCampaignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if len(req.Steps) > 0 {
+		if campaign.Status == models.CampaignStatusActive {
+			c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Pause the campaign before changing its steps"})
+			return
+		}
+		if err := service.ValidateCampaignSteps(req.Steps); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		campaign.Steps = req.Steps
+	}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		campaign.Name = name
+	}
+
+	leads, invalid := service.NewCampaignLeads(campaign.ID, req.ProfileURLs)
+	if len(invalid) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":        "Some entries are not LinkedIn profile URLs",
+			"invalid_urls": invalid,
+		})
+		return
+	}
+
+	added, err := h.campaigns.WithContext(c.Request.Context()).AddLeads(leads)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to add leads"})
+		return
+	}
+	// New leads reopen a completed campaign
+	if added > 0 && campaign.Status == models.CampaignStatusCompleted {
+		campaign.Status = models.CampaignStatusActive
+	}
+
+	if err := h.campaigns.WithContext(c.Request.Context()).Update(campaign); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update campaign"})
+		return
+	}
+
+	h.respond(c, http.StatusOK, campaign)
+}
+
+// StartCampaign activates a draft or paused campaign
+func (h *CampaignsHandler) StartCampaign(c *gin.Context) {
+	campaign, ok := h.findOwnedCampaign(c)
+	if !ok {
+		return
+	}
+
+	switch campaign.Status {
+	case models.CampaignStatusActive:
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Campaign is already active"})
+		return
+	case models.CampaignStatusCompleted:
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Campaign is completed; add leads to reopen it"})
+		return
+	}
+
+	stats, err := h.campaigns.WithContext(c.Request.Context()).Stats(campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch campaign stats"})
+		return
+	}
+	if stats.Active == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Campaign has no leads to contact"})
+		return
+	}
+
+	h.setStatus(c, campaign, models.CampaignStatusActive)
+}
+
+// PauseCampaign stops advancing an active campaign. Actions already queued
+// are still sent unless cancelled through the queue.
+func (h *CampaignsHandler) PauseCampaign(c *gin.Context) {
+	campaign, ok := h.findOwnedCampaign(c)
+	if !ok {
+		return
+	}
+
+	if campaign.Status != models.CampaignStatusActive {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Only active campaigns can be paused"})
+		return
+	}
+
+	h.setStatus(c, campaign, models.CampaignStatusPaused)
+}
+
+// DeleteCampaign deletes a campaign and its leads, cancelling their queued actions
+func (h *CampaignsHandler) DeleteCampaign(c *gin.Context) {
+	campaign, ok := h.findOwnedCampaign(c)
+	if !ok {
+		return
+	}
+
+	ids, err := h.campaigns.WithContext(c.Request.Context()).QueuedActionIDs(campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete campaign"})
+		return
+	}
+	if _, err := h.actions.WithContext(c.Request.Context()).CancelByIDs(ids, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to cancel queued actions"})
+		return
+	}
+
+	if err := h.campaigns.WithContext(c.Request.Context()).Delete(campaign); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete campaign"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Campaign deleted successfully"})
+}
+
+// ListCampaignLeads lists the leads of a campaign with their progress. The
+// status query parameter filters by lead status.
+func (h *CampaignsHandler) ListCampaignLeads(c *gin.Context) {
+	campaign, ok := h.findOwnedCampaign(c)
+	if !ok {
+		return
+	}
+
+	leads, err := h.campaigns.WithContext(c.Request.Context()).FindLeads(campaign.ID, strings.ToUpper(c.Query("status")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch leads"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CampaignLeadListResponse{
+		Leads: leads,
+		Count: len(leads),
+	})
+}
+
+// setStatus saves a new campaign status and writes the campaign
+func (h *CampaignsHandler) setStatus(c *gin.Context, campaign *models.Campaign, status string) {
+	campaign.Status = status
+	if err := h.campaigns.WithContext(c.Request.Context()).Update(campaign); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update campaign"})
+		return
+	}
+	h.respond(c, http.StatusOK, campaign)
+}
+
+// respond writes a campaign with its stats
+func (h *CampaignsHandler) respond(c *gin.Context, status int, campaign *models.Campaign) {
+	stats, err := h.campaigns.WithContext(c.Request.Context()).Stats(campaign.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch campaign stats"})
+		return
+	}
+	c.JSON(status, models.CampaignResponse{Campaign: *campaign, Stats: stats})
+}
+
+// findOwnedCampaign loads the campaign named by the :id path parameter,
+// checking it belongs to the authenticated user. On failure it writes the
+// error response and returns false.
+func (h *CampaignsHandler) findOwnedCampaign(c *gin.Context) (*models.Campaign, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid campaign ID"})
+		return nil, false
+	}
+
+	campaign, err := h.campaigns.WithContext(c.Request.Context()).FindByUserIDAndID(c.GetUint("user_id"), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Campaign not found"})
+		return nil, false
+	}
+	return campaign, true
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
+)
+
+// ListProviders lists the providers accounts can be connected to and the
+// auth methods each accepts
+func (h *LinkedInHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"providers": service.Providers(),
+	})
+}
+
+// Connect connects an account of the :provider path parameter. The body is
+// validated against the provider's auth methods; like LinkedIn, the response
+// may be a checkpoint to finish with SolveCheckpoint, such as a QR code to
+// scan for WhatsApp and Telegram.
+func (h *LinkedInHandler) Connect(c *gin.Context) {
+	provider, err := service.FindProvider(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Unknown provider"})
+		return
+	}
+
+	var req models.ConnectAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	connect, err := provider.NewConnectRequest(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	userID := c.GetUint("user_id")
+
+	resp, err := h.unipile.ConnectAccount(c.Request.Context(), connect)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Unipile connection failed", "provider", provider.Name, "user_id", userID, "error", err)
+		c.JSON(unipileErrorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	h.handleConnectResponse(c, userID, provider, resp, nil)
+}
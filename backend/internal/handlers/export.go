@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
+)
+
+// exportColumn is a column of an export and how to read it from a row
+type exportColumn[T any] struct {
+	name  string
+	value func(*T) string
+}
+
+// exportDataset is a table users can export
+type exportDataset struct {
+	columns []string
+	// stream reads the rows matching filter a batch at a time and passes on
+	// the values of the selected columns
+	stream func(repo *repository.ExportRepository, filter repository.ExportFilter, selected []int, fn func([][]string) error) error
+}
+
+// newExportDataset describes an exportable table by its columns and the
+// repository method streaming its rows
+func newExportDataset[T any](columns []exportColumn[T], find func(*repository.ExportRepository, repository.ExportFilter, func([]T) error) error) exportDataset {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+
+	return exportDataset{
+		columns: names,
+		stream: func(repo *repository.ExportRepository, filter repository.ExportFilter, selected []int, fn func([][]string) error) error {
+			return find(repo, filter, func(batch []T) error {
+				rows := make([][]string, len(batch))
+				for i := range batch {
+					rows[i] = make([]string, len(selected))
+					for j, column := range selected {
+						rows[i][j] = columns[column].value(&batch[i])
+					}
+				}
+				return fn(rows)
+			})
+		},
+	}
+}
+
+// exportDatasets are the tables served by GET /api/export/:dataset
+var exportDatasets = map[string]exportDataset{
+	"accounts": newExportDataset([]exportColumn[models.LinkedAccount]{
+		{"id", func(a *models.LinkedAccount) string { return formatID(a.ID) }},
+		{"provider", func(a *models.LinkedAccount) string { return a.Provider }},
+		{"account_id", func(a *models.LinkedAccount) string { return a.AccountID }},
+		{"account_name", func(a *models.LinkedAccount) string { return a.AccountName }},
+		{"public_identifier", func(a *models.LinkedAccount) string { return a.PublicIdentifier }},
+		{"profile_url", func(a *models.LinkedAccount) string { return a.ProfileURL }},
+		{"headline", func(a *models.LinkedAccount) string { return a.Headline }},
+		{"premium", func(a *models.LinkedAccount) string { return strconv.FormatBool(a.Premium) }},
+		{"sales_navigator", func(a *models.LinkedAccount) string { return strconv.FormatBool(a.SalesNavigator) }},
+		{"status", func(a *models.LinkedAccount) string { return a.Status }},
+		{"last_error", func(a *models.LinkedAccount) string { return a.LastError }},
+		{"last_checked_at", func(a *models.LinkedAccount) string { return formatTimePtr(a.LastCheckedAt) }},
+		{"timezone", func(a *models.LinkedAccount) string { return a.Timezone }},
+		{"working_hours_start", func(a *models.LinkedAccount) string { return a.WorkingHoursStart }},
+		{"working_hours_end", func(a *models.LinkedAccount) string { return a.WorkingHoursEnd }},
+		{"working_days", func(a *models.LinkedAccount) string { return a.WorkingDays }},
+		{"created_at", func(a *models.LinkedAccount) string { return formatTime(a.CreatedAt) }},
+	}, (*repository.ExportRepository).Accounts),
+
+	"leads": newExportDataset([]exportColumn[models.Lead]{
+		{"id", func(l *models.Lead) string { return formatID(l.ID) }},
+		{"profile_url", func(l *models.Lead) string { return l.ProfileURL }},
+		{"public_identifier", func(l *models.Lead) string { return l.PublicIdentifier }},
+		{"first_name", func(l *models.Lead) string { return l.FirstName }},
+		{"last_name", func(l *models.Lead) string { return l.LastName }},
+		{"company", func(l *models.Lead) string { return l.Company }},
+		{"custom_fields", func(l *models.Lead) string { return formatJSON(l.CustomFields) }},
+		{"created_at", func(l *models.Lead) string { return formatTime(l.CreatedAt) }},
+	}, (*repository.ExportRepository).Leads),
+
+	"chats": newExportDataset([]exportColumn[models.SyncedChat]{
+		{"account_id", func(c *models.SyncedChat) string { return formatID(c.LinkedAccountID) }},
+		{"chat_id", func(c *models.SyncedChat) string { return c.ChatID }},
+		{"name", func(c *models.SyncedChat) string { return c.Name }},
+		{"type", func(c *models.SyncedChat) string { return c.Type }},
+		{"attendee_provider_id", func(c *models.SyncedChat) string { return c.AttendeeProviderID }},
+		{"unread_count", func(c *models.SyncedChat) string { return strconv.Itoa(c.UnreadCount) }},
+		{"last_activity_at", func(c *models.SyncedChat) string { return formatTimePtr(c.LastActivityAt) }},
+	}, (*repository.ExportRepository).Chats),
+
+	"messages": newExportDataset([]exportColumn[models.SyncedMessage]{
+		{"account_id", func(m *models.SyncedMessage) string { return formatID(m.LinkedAccountID) }},
+		{"chat_id", func(m *models.SyncedMessage) string { return m.ChatID }},
+		{"message_id", func(m *models.SyncedMessage) string { return m.MessageID }},
+		{"sender_id", func(m *models.SyncedMessage) string { return m.SenderID }},
+		{"sender_name", func(m *models.SyncedMessage) string { return m.SenderName }},
+		{"is_me", func(m *models.SyncedMessage) string { return strconv.FormatBool(m.IsMe) }},
+		{"text", func(m *models.SyncedMessage) string { return m.Text }},
+		{"sent_at", func(m *models.SyncedMessage) string { return formatTimePtr(m.SentAt) }},
+	}, (*repository.ExportRepository).Messages),
+
+	"invitations": newExportDataset([]exportColumn[models.Invitation]{
+		{"id", func(i *models.Invitation) string { return formatID(i.ID) }},
+		{"account_id", func(i *models.Invitation) string { return formatID(i.LinkedAccountID) }},
+		{"recipient_provider_id", func(i *models.Invitation) string { return i.RecipientProviderID }},
+		{"recipient_identifier", func(i *models.Invitation) string { return i.RecipientIdentifier }},
+		{"recipient_name", func(i *models.Invitation) string { return i.RecipientName }},
+		{"note", func(i *models.Invitation) string { return i.Note }},
+		{"status", func(i *models.Invitation) string { return i.Status }},
+		{"sent_at", func(i *models.Invitation) string { return formatTime(i.SentAt) }},
+		{"accepted_at", func(i *models.Invitation) string { return formatTimePtr(i.AcceptedAt) }},
+		{"withdrawn_at", func(i *models.Invitation) string { return formatTimePtr(i.WithdrawnAt) }},
+	}, (*repository.ExportRepository).Invitations),
+
+	"queue": newExportDataset([]exportColumn[models.QueuedAction]{
+		{"id", func(a *models.QueuedAction) string { return formatID(a.ID) }},
+		{"account_id", func(a *models.QueuedAction) string { return formatID(a.LinkedAccountID) }},
+		{"type", func(a *models.QueuedAction) string { return a.Type }},
+		{"target", func(a *models.QueuedAction) string { return a.Target }},
+		{"recipient_identifier", func(a *models.QueuedAction) string { return a.Payload.RecipientIdentifier }},
+		{"text", func(a *models.QueuedAction) string { return a.Payload.Text }},
+		{"note", func(a *models.QueuedAction) string { return a.Payload.Note }},
+		{"status", func(a *models.QueuedAction) string { return a.Status }},
+		{"scheduled_at", func(a *models.QueuedAction) string { return formatTime(a.ScheduledAt) }},
+		{"attempts", func(a *models.QueuedAction) string { return strconv.Itoa(a.Attempts) }},
+		{"last_error", func(a *models.QueuedAction) string { return a.LastError }},
+		{"result_id", func(a *models.QueuedAction) string { return a.ResultID }},
+		{"completed_at", func(a *models.QueuedAction) string { return formatTimePtr(a.CompletedAt) }},
+		{"created_at", func(a *models.QueuedAction) string { return formatTime(a.CreatedAt) }},
+	}, (*repository.ExportRepository).QueuedActions),
+
+	"campaigns": newExportDataset([]exportColumn[models.Campaign]{
+		{"id", func(c *models.Campaign) string { return formatID(c.ID) }},
+		{"account_id", func(c *models.Campaign) string { return formatID(c.LinkedAccountID) }},
+		{"name", func(c *models.Campaign) string { return c.Name }},
+		{"status", func(c *models.Campaign) string { return c.Status }},
+		{"steps", func(c *models.Campaign) string { return strconv.Itoa(len(c.Steps)) }},
+		{"created_at", func(c *models.Campaign) string { return formatTime(c.CreatedAt) }},
+	}, (*repository.ExportRepository).Campaigns),
+}
+
+// ExportHandler handles data export requests
+type ExportHandler struct {
+	exports *repository.ExportRepository
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(exports *repository.ExportRepository) *ExportHandler {
+	return &ExportHandler{exports: exports}
+}
+
+// Export streams one of the user's tables as CSV or XLSX. Query parameters:
+// format (csv or xlsx, default csv), columns (comma-separated, default all)
+// and from/to to filter on the table's date column.
+func (h *ExportHandler) Export(c *gin.Context) {
+	name := c.Param("dataset")
+	dataset, ok := exportDatasets[name]
+	if !ok {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Unknown export " + strconv.Quote(name)})
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", service.ExportFormatCSV))
+	if format != service.ExportFormatCSV && format != service.ExportFormatXLSX {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "format must be csv or xlsx"})
+		return
+	}
+
+	selected, ok := exportColumns(c, dataset.columns)
+	if !ok {
+		return
+	}
+
+	filter := repository.ExportFilter{UserID: c.GetUint("user_id")}
+	if filter.From, ok = exportDate(c, "from", false); !ok {
+		return
+	}
+	if filter.To, ok = exportDate(c, "to", true); !ok {
+		return
+	}
+
+	// From here on the response is streamed, so errors can only be logged
+	filename := fmt.Sprintf("%s-%s.%s", name, time.Now().UTC().Format("2006-01-02"), format)
+	c.Header("Content-Type", service.ExportContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	w, err := service.NewExportWriter(format, c.Writer, name)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to start export", "dataset", name, "error", err)
+		return
+	}
+
+	header := make([]string, len(selected))
+	for i, column := range selected {
+		header[i] = dataset.columns[column]
+	}
+	err = w.WriteRow(header)
+	if err == nil {
+		err = dataset.stream(h.exports.WithContext(c.Request.Context()), filter, selected, func(rows [][]string) error {
+			for _, row := range rows {
+				if err := w.WriteRow(row); err != nil {
+					return err
+				}
+			}
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+			return nil
+		})
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Export failed", "dataset", name, "user_id", filter.UserID, "error", err)
+	}
+}
+
+// exportColumns resolves the columns query parameter to column indexes,
+// defaulting to every column. On invalid input it writes a 400 and returns
+// false.
+func exportColumns(c *gin.Context, columns []string) ([]int, bool) {
+	value := strings.TrimSpace(c.Query("columns"))
+	if value == "" {
+		selected := make([]int, len(columns))
+		for i := range columns {
+			selected[i] = i
+		}
+		return selected, true
+	}
+
+	index := make(map[string]int, len(columns))
+	for i, name := range columns {
+		index[name] = i
+	}
+
+	var selected []int
+	for _, name := range strings.Split(value, ",") {
+		i, ok := index[strings.TrimSpace(name)]
+		if !ok {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{
+				Error: fmt.Sprintf("Unknown column %q; available columns: %s", strings.TrimSpace(name), strings.Join(columns, ", ")),
+			})
+			return nil, false
+		}
+		selected = append(selected, i)
+	}
+	return selected, true
+}
+
+// exportDate reads a date filter given as RFC 3339 or YYYY-MM-DD. A plain
+// date used as the end of the range includes that whole day. On invalid input
+// it writes a 400 and returns false.
+func exportDate(c *gin.Context, param string, end bool) (*time.Time, bool) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, true
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, true
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: param + " must be a date (YYYY-MM-DD) or an RFC 3339 time"})
+		return nil, false
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, true
+}
+
+func formatID(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return formatTime(*t)
+}
+
+func formatJSON(value map[string]string) string {
+	if len(value) == 0 {
+		return ""
+	}
+	var out strings.Builder
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
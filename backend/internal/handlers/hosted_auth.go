@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
+)
+
+// ConnectLinkedInHosted creates a link to Unipile's hosted auth wizard, where
+// the user connects their LinkedIn account without their credentials passing
+// through this API. Unipile then calls HostedAuthNotify.
+func (h *LinkedInHandler) ConnectLinkedInHosted(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	link, expiresAt, err := h.hostedAuth.CreateLink(c.Request.Context(), userID)
+	if errors.Is(err, service.ErrHostedAuthDisabled) {
+		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "Hosted auth is not configured"})
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to create hosted auth link", "user_id", userID, "error", err)
+		var apiErr *service.APIError
+		if errors.As(err, &apiErr) {
+			c.JSON(unipileErrorStatus(err), models.ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create hosted auth link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.HostedAuthLinkResponse{
+		URL:       link.URL,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// HostedAuthNotify receives the account connected through a hosted auth link.
+// The link's signed state names the user; each link connects one account.
+func (h *LinkedInHandler) HostedAuthNotify(c *gin.Context) {
+	var notification models.HostedAuthNotification
+	if err := c.ShouldBindJSON(&notification); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if notification.Status != "CREATION_SUCCESS" || notification.AccountID == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Unsupported notification"})
+		return
+	}
+
+	// Check the signature before calling Unipile on the caller's behalf
+	verified, err := h.hostedAuth.Verify(notification.Name)
+	if err != nil || !h.hostedAuth.VerifyNotifyKey(verified, c.Query("key")) {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid state"})
+		return
+	}
+
+	// An account connected by one user can't be claimed through another's link
+	linked, err := h.accounts.WithContext(c.Request.Context()).FindByAccountID(notification.AccountID)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to look up hosted auth account", "unipile_account_id", notification.AccountID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save account"})
+		return
+	}
+	for i := range linked {
+		if linked[i].UserID != verified.UserID {
+			slog.WarnContext(c.Request.Context(), "Hosted auth notification for another user's account", "unipile_account_id", notification.AccountID, "user_id", verified.UserID)
+			c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Account is linked to another user"})
+			return
+		}
+	}
+
+	// Only trust account IDs Unipile knows as LinkedIn accounts
+	account, err := h.unipile.GetAccount(c.Request.Context(), notification.AccountID)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to fetch hosted auth account", "unipile_account_id", notification.AccountID, "error", err)
+		c.JSON(unipileErrorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if account.Type != service.LinkedIn.UnipileType {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Not a LinkedIn account"})
+		return
+	}
+
+	state, err := h.hostedAuth.Consume(c.Request.Context(), notification.Name, notification.AccountID)
+	switch {
+	case errors.Is(err, service.ErrInvalidHostedAuthState):
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid state"})
+		return
+	case errors.Is(err, service.ErrHostedAuthStateExpired):
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Hosted auth link expired"})
+		return
+	case errors.Is(err, service.ErrHostedAuthStateUsed):
+		slog.WarnContext(c.Request.Context(), "Replayed hosted auth notification", "unipile_account_id", notification.AccountID)
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Hosted auth link already used"})
+		return
+	case err != nil:
+		slog.ErrorContext(c.Request.Context(), "Failed to consume hosted auth session", "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to verify state"})
+		return
+	}
+
+	slog.InfoContext(c.Request.Context(), "Hosted auth connected account", "unipile_account_id", notification.AccountID, "user_id", state.UserID)
+	h.saveAccount(c, state.UserID, service.LinkedIn, &service.ConnectResponse{
+		AccountID: notification.AccountID,
+		Name:      account.Name,
+	})
+}
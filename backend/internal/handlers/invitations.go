@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
+	"gorm.io/gorm"
+)
+
+// InvitationsHandler handles LinkedIn connection requests sent from linked accounts
+type InvitationsHandler struct {
+	unipile     service.UnipileClient
+	accounts    *repository.LinkedAccountRepository
+	invitations *repository.InvitationRepository
+	templates   *repository.TemplateRepository
+	profiles    *service.ProfileService
+	queue       *service.ActionQueue
+}
+
+// NewInvitationsHandler creates a new invitations handler
+func NewInvitationsHandler(unipile service.UnipileClient, accounts *repository.LinkedAccountRepository, invitations *repository.InvitationRepository, templates *repository.TemplateRepository, profiles *service.ProfileService, queue *service.ActionQueue) *InvitationsHandler {
+	return &InvitationsHandler{
+		unipile:     unipile,
+		accounts:    accounts,
+		invitations: invitations,
+		templates:   templates,
+		profiles:    profiles,
+		queue:       queue,
+	}
+}
+
+// SendInvitation queues a connection request to a LinkedIn member named by
+// provider ID or profile URL, with an optional personalized note given as
+// text or rendered from a template. The invitation is stored once the queue
+// has sent it.
+func (h *InvitationsHandler) SendInvitation(c *gin.Context) {
+	account, ok := findOwnedAccount(c, h.accounts)
+	if !ok {
+		return
+	}
+
+	var req models.SendInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+	note := strings.TrimSpace(req.Note)
+
+	var tmpl *service.MessageTemplate
+	if req.TemplateID != 0 {
+		if tmpl, ok = findTemplate(c, h.templates, req.TemplateID); !ok {
+			return
+		}
+	}
+
+	action := &models.QueuedAction{
+		UserID:          account.UserID,
+		LinkedAccountID: account.ID,
+		Type:            models.QueuedActionSendInvitation,
+		Target:          strings.TrimSpace(req.ProviderID),
+		Payload:         models.ActionPayload{Note: note},
+	}
+
+	// Profile URLs carry the public identifier, which Unipile resolves to the
+	// provider ID invitations must be sent to. Templates need the profile too.
+	if action.Target == "" || tmpl != nil {
+		identifier := action.Target
+		if identifier == "" {
+			if identifier, ok = service.ParseProfileIdentifier(req.ProfileURL); !ok {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "profile_url is not a LinkedIn profile URL"})
+				return
+			}
+		}
+
+		profile, ok := lookupProfile(c, h.profiles, account, identifier)
+		if !ok {
+			return
+		}
+
+		action.Target = profile.ProviderID
+		action.Payload.RecipientIdentifier = profile.PublicIdentifier
+		action.Payload.RecipientName = profile.FullName
+
+		if tmpl != nil {
+			if note, ok = renderForRecipient(c, tmpl, profile); !ok {
+				return
+			}
+			if len([]rune(note)) > models.InvitationNoteMaxLength {
+				c.JSON(http.StatusBadRequest, models.ErrorResponse{
+					Error: fmt.Sprintf("Rendered note is longer than %d characters", models.InvitationNoteMaxLength),
+				})
+				return
+			}
+			action.Payload.Note = note
+		}
+	}
+
+	queued, err := h.queue.HasPending(c.Request.Context(), account.ID, models.QueuedActionSendInvitation, action.Target)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to check existing invitations"})
+		return
+	}
+	if queued {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "An invitation to this member is already queued"})
+		return
+	}
+	if _, err := h.invitations.WithContext(c.Request.Context()).FindPendingByRecipient(account.ID, action.Target); err == nil {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "An invitation to this member is already pending"})
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to check existing invitations"})
+		return
+	}
+
+	enqueueAction(c, h.queue, action, req.SendAt, "Invitation queued")
+}
+
+// ListInvitations lists the invitations sent from a linked account, newest
+// first. The status query parameter filters by PENDING, ACCEPTED or WITHDRAWN.
+func (h *InvitationsHandler) ListInvitations(c *gin.Context) {
+	account, ok := findOwnedAccount(c, h.accounts)
+	if !ok {
+		return
+	}
+
+	status := strings.ToUpper(c.Query("status"))
+	switch status {
+	case "", models.InvitationStatusPending, models.InvitationStatusAccepted, models.InvitationStatusWithdrawn:
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "status must be PENDING, ACCEPTED or WITHDRAWN"})
+		return
+	}
+
+	invitations, err := h.invitations.WithContext(c.Request.Context()).FindByLinkedAccountID(account.ID, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.InvitationListResponse{
+		Invitations: invitations,
+		Count:       len(invitations),
+	})
+}
+
+// WithdrawInvitation withdraws a pending invitation on LinkedIn and marks it
+// withdrawn locally
+func (h *InvitationsHandler) WithdrawInvitation(c *gin.Context) {
+	account, ok := findOwnedAccount(c, h.accounts)
+	if !ok {
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("invitationId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid invitation ID"})
+		return
+	}
+
+	invitation, err := h.invitations.WithContext(c.Request.Context()).FindByLinkedAccountIDAndID(account.ID, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Invitation not found"})
+		return
+	}
+	if invitation.Status != models.InvitationStatusPending {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Only pending invitations can be withdrawn"})
+		return
+	}
+
+	if err := h.unipile.CancelInvitation(c.Request.Context(), account.AccountID, invitation.InvitationID); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to withdraw invitation", "invitation_id", invitation.ID, "error", err)
+		c.JSON(unipileErrorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	now := time.Now()
+	invitation.Status = models.InvitationStatusWithdrawn
+	invitation.WithdrawnAt = &now
+	if err := h.invitations.WithContext(c.Request.Context()).Update(invitation); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Invitation withdrawn but failed to save it"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invitation)
+}
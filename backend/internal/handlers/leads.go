@@ -0,0 +1,392 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
+)
+
+const (
+	// maxLeadImportSize caps the size of uploaded lead files
+	maxLeadImportSize = 10 << 20
+	maxLeadListName   = 200
+)
+
+// LeadsHandler handles imported leads and lead lists
+type LeadsHandler struct {
+	leads     *repository.LeadRepository
+	accounts  *repository.LinkedAccountRepository
+	templates *repository.TemplateRepository
+	queue     *service.ActionQueue
+}
+
+// NewLeadsHandler creates a new leads handler
+func NewLeadsHandler(leads *repository.LeadRepository, accounts *repository.LinkedAccountRepository, templates *repository.TemplateRepository, queue *service.ActionQueue) *LeadsHandler {
+	return &LeadsHandler{
+		leads:     leads,
+		accounts:  accounts,
+		templates: templates,
+		queue:     queue,
+	}
+}
+
+// ImportLeads imports leads from an uploaded CSV file. Leads already imported
+// are updated with the file's non-empty cells. When a list name is given,
+// every imported lead is added to that list, which is created if needed.
+func (h *LeadsHandler) ImportLeads(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxLeadImportSize)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, models.ErrorResponse{Error: "The file is larger than 10 MB"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "A CSV file is required in the file field"})
+		return
+	}
+
+	var mapping models.LeadColumnMapping
+	if value := c.PostForm("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "mapping must be a JSON object: " + err.Error()})
+			return
+		}
+	}
+
+	listName := strings.TrimSpace(c.PostForm("list"))
+	if len(listName) > maxLeadListName {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "list must be at most 200 characters"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Failed to read the file"})
+		return
+	}
+	defer file.Close()
+
+	parsed, err := service.ParseLeadsCSV(file, mapping)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	identifiers := make([]string, len(parsed.Leads))
+	for i := range parsed.Leads {
+		identifiers[i] = parsed.Leads[i].PublicIdentifier
+	}
+	existing, err := h.leads.WithContext(c.Request.Context()).FindByIdentifiers(userID, identifiers)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to check existing leads"})
+		return
+	}
+	byIdentifier := make(map[string]*models.Lead, len(existing))
+	for i := range existing {
+		byIdentifier[existing[i].PublicIdentifier] = &existing[i]
+	}
+
+	resp := models.LeadImportResponse{
+		Rows:       parsed.Rows,
+		Duplicates: parsed.Duplicates,
+		Errors:     parsed.Errors,
+	}
+	leads := make([]models.Lead, 0, len(parsed.Leads))
+	for _, lead := range parsed.Leads {
+		if current, ok := byIdentifier[lead.PublicIdentifier]; ok {
+			service.MergeLead(current, &lead)
+			leads = append(leads, *current)
+			resp.Updated++
+			continue
+		}
+		lead.UserID = userID
+		leads = append(leads, lead)
+		resp.Created++
+	}
+
+	if listName != "" {
+		if resp.List, err = h.leads.WithContext(c.Request.Context()).FindOrCreateList(userID, listName); err != nil {
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create lead list"})
+			return
+		}
+	}
+
+	if err := h.leads.WithContext(c.Request.Context()).SaveImport(leads, resp.List); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to import leads", "user_id", userID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save leads"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListLeads lists the user's leads in import order, with cursor pagination
+// (cursor, limit). The list_id query parameter narrows it to one list.
+func (h *LeadsHandler) ListLeads(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+
+	var afterID uint64
+	if cursor := c.Query("cursor"); cursor != "" {
+		var err error
+		if afterID, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid cursor"})
+			return
+		}
+	}
+
+	var listID uint64
+	if value := c.Query("list_id"); value != "" {
+		var err error
+		if listID, err = strconv.ParseUint(value, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid list ID"})
+			return
+		}
+		if _, err := h.leads.WithContext(c.Request.Context()).FindListByUserIDAndID(userID, uint(listID)); err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Lead list not found"})
+			return
+		}
+	}
+
+	leads, err := h.leads.WithContext(c.Request.Context()).FindByUserID(userID, uint(listID), uint(afterID), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch leads"})
+		return
+	}
+
+	resp := models.LeadListResponse{Leads: leads, Count: len(leads)}
+	if len(leads) == limit {
+		resp.NextCursor = strconv.FormatUint(uint64(leads[len(leads)-1].ID), 10)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteLead deletes a lead and removes it from its lists
+func (h *LeadsHandler) DeleteLead(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid lead ID"})
+		return
+	}
+
+	lead, err := h.leads.WithContext(c.Request.Context()).FindByUserIDAndID(c.GetUint("user_id"), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Lead not found"})
+		return
+	}
+
+	if err := h.leads.WithContext(c.Request.Context()).Delete(lead); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete lead"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Lead deleted successfully"})
+}
+
+// ListLeadLists lists the user's lead lists with their lead counts
+func (h *LeadsHandler) ListLeadLists(c *gin.Context) {
+	lists, err := h.leads.WithContext(c.Request.Context()).FindLists(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch lead lists"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.LeadListsResponse{
+		Lists: lists,
+		Count: len(lists),
+	})
+}
+
+// GetLeadList returns a lead list with its lead count
+func (h *LeadsHandler) GetLeadList(c *gin.Context) {
+	list, ok := h.findOwnedList(c)
+	if !ok {
+		return
+	}
+
+	count, err := h.leads.WithContext(c.Request.Context()).CountListLeads(list.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to count leads"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.LeadListSummary{LeadList: *list, LeadCount: count})
+}
+
+// DeleteLeadList deletes a lead list, keeping its leads
+func (h *LeadsHandler) DeleteLeadList(c *gin.Context) {
+	list, ok := h.findOwnedList(c)
+	if !ok {
+		return
+	}
+
+	if err := h.leads.WithContext(c.Request.Context()).DeleteList(list); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete lead list"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Lead list deleted successfully"})
+}
+
+// MessageLeadList queues a message to every lead of a list from one linked
+// account. Templates are rendered from each lead's imported fields; leads
+// missing a variable, and leads the account already has a message queued
+// for, are skipped and reported. The messages are queued all at once, so a
+// failed request can be retried. Recipients are resolved from their profile
+// URL when the message is sent.
+func (h *LeadsHandler) MessageLeadList(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	list, ok := h.findOwnedList(c)
+	if !ok {
+		return
+	}
+
+	var req models.BulkMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	account, err := h.accounts.WithContext(c.Request.Context()).FindByUserIDAndID(userID, req.AccountID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Account not found"})
+		return
+	}
+
+	var tmpl *service.MessageTemplate
+	if req.TemplateID != 0 {
+		if tmpl, ok = findTemplate(c, h.templates, req.TemplateID); !ok {
+			return
+		}
+	}
+
+	leads, err := h.leads.WithContext(c.Request.Context()).FindListLeads(list.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch leads"})
+		return
+	}
+	if len(leads) == 0 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "The list has no leads"})
+		return
+	}
+
+	if !checkQuota(c, h.queue, account.ID, models.QueuedActionStartChat, req.SendAt) {
+		return
+	}
+
+	// A retried request mustn't message the same leads twice
+	queued, err := h.queue.PendingRecipients(c.Request.Context(), account.ID, models.QueuedActionStartChat)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to load queued messages", "account_id", account.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to queue action"})
+		return
+	}
+
+	resp := models.BulkMessageResponse{
+		Message: "Messages queued",
+		Skipped: []models.BulkMessageSkip{},
+	}
+	actions := make([]models.QueuedAction, 0, len(leads))
+	for i := range leads {
+		lead := &leads[i]
+
+		if queued[lead.PublicIdentifier] {
+			resp.Skipped = append(resp.Skipped, models.BulkMessageSkip{
+				LeadID:           lead.ID,
+				PublicIdentifier: lead.PublicIdentifier,
+				Error:            "A message to this lead is already queued",
+			})
+			continue
+		}
+
+		text := req.Text
+		if tmpl != nil {
+			var skip *models.BulkMessageSkip
+			if text, skip = renderForLead(tmpl, lead); skip != nil {
+				resp.Skipped = append(resp.Skipped, *skip)
+				continue
+			}
+		}
+
+		action := models.QueuedAction{
+			UserID:          userID,
+			LinkedAccountID: account.ID,
+			Type:            models.QueuedActionStartChat,
+			Payload: models.ActionPayload{
+				Text:                text,
+				RecipientIdentifier: lead.PublicIdentifier,
+				RecipientName:       strings.TrimSpace(lead.FirstName + " " + lead.LastName),
+			},
+		}
+		if req.SendAt != nil {
+			action.ScheduledAt = *req.SendAt
+		}
+		actions = append(actions, action)
+		queued[lead.PublicIdentifier] = true
+	}
+
+	if len(actions) > 0 {
+		if err := h.queue.EnqueueAll(c.Request.Context(), actions); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Failed to queue messages to leads", "lead_list_id", list.ID, "account_id", account.ID, "error", err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to queue action"})
+			return
+		}
+	}
+	resp.Queued = len(actions)
+
+	c.JSON(http.StatusAccepted, resp)
+}
+
+// renderForLead renders a template from a lead's imported fields, or
+// explains why the lead is skipped
+func renderForLead(tmpl *service.MessageTemplate, lead *models.Lead) (string, *models.BulkMessageSkip) {
+	skip := &models.BulkMessageSkip{LeadID: lead.ID, PublicIdentifier: lead.PublicIdentifier}
+
+	text, err := tmpl.Render(service.LeadTemplateData(lead))
+	var missing *service.MissingVariablesError
+	switch {
+	case errors.As(err, &missing):
+		skip.Error, skip.MissingVariables = "The lead has no value for some template variables", missing.Variables
+	case err != nil:
+		skip.Error = err.Error()
+	case text == "":
+		skip.Error = "Template rendered an empty message"
+	default:
+		return text, nil
+	}
+	return "", skip
+}
+
+// findOwnedList loads the lead list named by the :id path parameter,
+// checking it belongs to the authenticated user. On failure it writes the
+// error response and returns false.
+func (h *LeadsHandler) findOwnedList(c *gin.Context) (*models.LeadList, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid lead list ID"})
+		return nil, false
+	}
+
+	list, err := h.leads.WithContext(c.Request.Context()).FindListByUserIDAndID(c.GetUint("user_id"), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Lead list not found"})
+		return nil, false
+	}
+	return list, true
+}
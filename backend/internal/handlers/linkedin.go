@@ -1,49 +1,44 @@
 package handlers
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io"
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/johnson7543/chatsheet-assessment/internal/config"
-	"github.com/johnson7543/chatsheet-assessment/internal/database"
 	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
+	"gorm.io/gorm"
 )
 
-// UnipileConnectRequest represents the request to Unipile API for account connection
-type UnipileConnectRequest struct {
-	Provider    string `json:"provider"`
-	AccessToken string `json:"access_token,omitempty"` // For cookie auth (li_at cookie)
-	Username    string `json:"username,omitempty"`     // For credentials auth
-	Password    string `json:"password,omitempty"`     // For credentials auth
+// checkpointTTL is how long Unipile keeps a checkpoint open before the
+// connection attempt has to be restarted
+const checkpointTTL = 5 * time.Minute
+
+// LinkedInHandler handles account connection requests, for LinkedIn and the
+// other providers in the service.Providers registry
+type LinkedInHandler struct {
+	unipile    service.UnipileClient
+	accounts   *repository.LinkedAccountRepository
+	pending    *repository.PendingConnectionRepository
+	hostedAuth *service.HostedAuthService
 }
 
-// UnipileConnectResponse represents the response from Unipile API
-type UnipileConnectResponse struct {
-	// Success fields
-	AccountID string `json:"account_id"`
-	Provider  string `json:"provider"`
-	Name      string `json:"name,omitempty"`
-	Username  string `json:"username,omitempty"`
-	Status    any    `json:"status,omitempty"` // Can be string or number
-
-	// Error fields (multiple formats supported)
-	Error       string `json:"error,omitempty"`       // Generic error
-	Message     string `json:"message,omitempty"`     // Generic message
-	Description string `json:"description,omitempty"` // Error description
-
-	// Unipile specific error format
-	Type   string `json:"type,omitempty"`   // e.g., "errors/invalid_credentials"
-	Title  string `json:"title,omitempty"`  // e.g., "Invalid credentials"
-	Detail string `json:"detail,omitempty"` // e.g., "The provided credentials are invalid."
+// NewLinkedInHandler creates a new LinkedIn handler
+func NewLinkedInHandler(unipile service.UnipileClient, accounts *repository.LinkedAccountRepository, pending *repository.PendingConnectionRepository, hostedAuth *service.HostedAuthService) *LinkedInHandler {
+	return &LinkedInHandler{
+		unipile:    unipile,
+		accounts:   accounts,
+		pending:    pending,
+		hostedAuth: hostedAuth,
+	}
 }
 
 // ConnectLinkedInWithCookie handles LinkedIn connection using cookie authentication
-func ConnectLinkedInWithCookie(c *gin.Context) {
+func (h *LinkedInHandler) ConnectLinkedInWithCookie(c *gin.Context) {
 	var req models.LinkedInCookieRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
@@ -53,228 +48,339 @@ func ConnectLinkedInWithCookie(c *gin.Context) {
 	userID := c.GetUint("user_id")
 
 	// Call Unipile API with cookie authentication
-	unipileReq := UnipileConnectRequest{
-		Provider:    "LINKEDIN",
-		AccessToken: req.Cookie, // li_at cookie value
-	}
-
-	accountID, accountName, err := callUnipileAPI(unipileReq)
+	resp, err := h.unipile.ConnectWithCookie(c.Request.Context(), req.Cookie)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		c.JSON(unipileErrorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	// Save to database
-	linkedAccount := models.LinkedAccount{
-		UserID:      userID,
-		Provider:    "linkedin",
-		AccountID:   accountID,
-		AccountName: accountName,
-	}
-
-	if err := database.DB.Create(&linkedAccount).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save account"})
-		return
-	}
-
-	response := models.LinkedInConnectResponse{
-		Message:   "LinkedIn account connected successfully",
-		AccountID: accountID,
-		Account:   linkedAccount,
-	}
-
-	c.JSON(http.StatusOK, response)
+	h.handleConnectResponse(c, userID, service.LinkedIn, resp, nil)
 }
 
 // ConnectLinkedInWithCredentials handles LinkedIn connection using username/password
-func ConnectLinkedInWithCredentials(c *gin.Context) {
-	log.Println("=== LinkedIn Connect with Credentials START ===")
-
+func (h *LinkedInHandler) ConnectLinkedInWithCredentials(c *gin.Context) {
 	var req models.LinkedInCredentialsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Printf("ERROR: Failed to bind JSON request: %v", err)
+		slog.ErrorContext(c.Request.Context(), "Failed to bind JSON request", "error", err)
 		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
 	userID := c.GetUint("user_id")
-	log.Printf("User ID: %d", userID)
-	log.Printf("Username provided: %s", req.Username)
-	log.Printf("Password length: %d", len(req.Password))
 
 	// Call Unipile API with credentials
-	unipileReq := UnipileConnectRequest{
-		Provider: "LINKEDIN",
-		Username: req.Username,
-		Password: req.Password,
-	}
-
-	log.Println("Calling Unipile API...")
-	accountID, accountName, err := callUnipileAPI(unipileReq)
+	resp, err := h.unipile.ConnectWithCredentials(c.Request.Context(), req.Username, req.Password)
 	if err != nil {
-		log.Printf("ERROR: Unipile API call failed: %v", err)
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		slog.ErrorContext(c.Request.Context(), "Unipile credentials connection failed", "user_id", userID, "error", err)
+		c.JSON(unipileErrorStatus(err), models.ErrorResponse{Error: err.Error()})
 		return
 	}
 
-	log.Printf("SUCCESS: Received account_id: %s, account_name: %s", accountID, accountName)
+	slog.InfoContext(c.Request.Context(), "Received Unipile account", "unipile_account_id", resp.AccountID, "account_name", resp.DisplayName())
+	h.handleConnectResponse(c, userID, service.LinkedIn, resp, nil)
+}
 
-	// Save to database
-	log.Println("Saving to database...")
-	linkedAccount := models.LinkedAccount{
-		UserID:      userID,
-		Provider:    "linkedin",
-		AccountID:   accountID,
-		AccountName: accountName,
+// ReconnectAccount refreshes the session of an existing linked account with a
+// new li_at cookie or credentials, keeping the same LinkedAccount row
+func (h *LinkedInHandler) ReconnectAccount(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	account, ok := findOwnedAccount(c, h.accounts)
+	if !ok {
+		return
+	}
+	if account.Provider != models.ProviderLinkedIn {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Only LinkedIn accounts can be reconnected, connect the account again instead"})
+		return
 	}
 
-	if err := database.DB.Create(&linkedAccount).Error; err != nil {
-		log.Printf("ERROR: Failed to save to database: %v", err)
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save account"})
+	var req models.LinkedInReconnectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
 		return
 	}
-	log.Printf("Database saved successfully, ID: %d", linkedAccount.ID)
 
-	response := models.LinkedInConnectResponse{
-		Message:   "LinkedIn account connected successfully",
-		AccountID: accountID,
-		Account:   linkedAccount,
+	var (
+		resp *service.ConnectResponse
+		err  error
+	)
+	if req.Cookie != "" {
+		resp, err = h.unipile.ReconnectWithCookie(c.Request.Context(), account.AccountID, req.Cookie)
+	} else {
+		resp, err = h.unipile.ReconnectWithCredentials(c.Request.Context(), account.AccountID, req.Username, req.Password)
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Unipile reconnect failed", "account_id", account.ID, "error", err)
+		c.JSON(unipileErrorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
 	}
 
-	log.Println("=== LinkedIn Connect with Credentials END (SUCCESS) ===")
-	c.JSON(http.StatusOK, response)
+	h.handleConnectResponse(c, userID, service.LinkedIn, resp, account)
 }
 
-// callUnipileAPI makes the actual API call to Unipile
-func callUnipileAPI(req UnipileConnectRequest) (accountID, accountName string, err error) {
-	log.Println("--- callUnipileAPI START ---")
-
-	// Check if API key is configured
-	if config.App.UnipileAPIKey == "" {
-		log.Println("ERROR: Unipile API key is not configured")
-		return "", "", fmt.Errorf("config error: Unipile API key is not configured")
+// SolveCheckpoint completes a connection that is waiting on a checkpoint. The
+// code is forwarded to Unipile; for IN_APP_VALIDATION no code is needed and the
+// account status is polled instead, so clients call this until it returns 200.
+func (h *LinkedInHandler) SolveCheckpoint(c *gin.Context) {
+	var req models.LinkedInCheckpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
 	}
-	log.Printf("Unipile API URL: %s", config.App.Unipile.APIURL)
 
-	// Prepare request body
-	jsonData, err := json.Marshal(req)
+	userID := c.GetUint("user_id")
+
+	pending, err := h.pending.WithContext(c.Request.Context()).FindByUserID(userID)
 	if err != nil {
-		log.Printf("ERROR: Failed to marshal request: %v", err)
-		return "", "", fmt.Errorf("failed to marshal request: %v", err)
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "No pending connection"})
+		return
+	}
+
+	if time.Now().After(pending.ExpiresAt) {
+		if err := h.pending.WithContext(c.Request.Context()).Delete(pending); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Failed to delete expired pending connection", "error", err)
+		}
+		c.JSON(http.StatusGone, models.ErrorResponse{Error: "Verification expired, please connect again"})
+		return
 	}
-	log.Printf("Request payload: %s", string(jsonData))
 
-	// Create HTTP request to Unipile API
-	url := fmt.Sprintf("%s/accounts", config.App.Unipile.APIURL)
-	log.Printf("Making POST request to: %s", url)
+	// A checkpoint raised while reconnecting completes on the existing row
+	var target *models.LinkedAccount
+	if pending.LinkedAccountID != nil {
+		target, err = h.accounts.WithContext(c.Request.Context()).FindByUserIDAndID(userID, *pending.LinkedAccountID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Account not found"})
+			return
+		}
+	}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	provider, err := service.FindProvider(pending.Provider)
 	if err != nil {
-		log.Printf("ERROR: Failed to create HTTP request: %v", err)
-		return "", "", fmt.Errorf("failed to create request: %v", err)
+		slog.ErrorContext(c.Request.Context(), "Pending connection has unknown provider", "pending_connection_id", pending.ID, "provider", pending.Provider)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Unknown provider"})
+		return
+	}
+
+	if service.IsPolledCheckpoint(pending.CheckpointType) && req.Code == "" {
+		h.pollCheckpoint(c, provider, pending, target)
+		return
 	}
 
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("X-API-KEY", config.App.UnipileAPIKey)
-	log.Printf("Request headers set: Content-Type=application/json, X-API-KEY=***%s", config.App.UnipileAPIKey[len(config.App.UnipileAPIKey)-4:])
+	if req.Code == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Verification code is required"})
+		return
+	}
 
-	// Make the request
-	log.Println("Sending request to Unipile...")
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	resp, err := h.unipile.SolveCheckpoint(c.Request.Context(), provider.UnipileType, pending.AccountID, req.Code)
 	if err != nil {
-		log.Printf("ERROR: HTTP request failed: %v", err)
-		return "", "", fmt.Errorf("failed to call Unipile API: %v", err)
+		slog.ErrorContext(c.Request.Context(), "Unipile checkpoint call failed", "error", err)
+		c.JSON(unipileErrorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
 	}
-	defer resp.Body.Close()
 
-	log.Printf("Response Status Code: %d", resp.StatusCode)
-	log.Printf("Response Headers: %v", resp.Header)
+	h.handleConnectResponse(c, userID, provider, resp, target)
+}
 
-	// Read response body
-	body, err := io.ReadAll(resp.Body)
+// pollCheckpoint checks whether the user has approved the login in the
+// provider's app, or scanned its QR code, and finishes the connection once
+// the account is OK
+func (h *LinkedInHandler) pollCheckpoint(c *gin.Context, provider *service.Provider, pending *models.PendingConnection, target *models.LinkedAccount) {
+	account, err := h.unipile.GetAccount(c.Request.Context(), pending.AccountID)
 	if err != nil {
-		log.Printf("ERROR: Failed to read response body: %v", err)
-		return "", "", fmt.Errorf("failed to read response: %v", err)
+		slog.ErrorContext(c.Request.Context(), "Failed to poll Unipile account", "unipile_account_id", pending.AccountID, "error", err)
+		c.JSON(unipileErrorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	if account.Status() != service.AccountStatusOK {
+		message := "Waiting for approval in the " + provider.DisplayName + " app"
+		if pending.CheckpointType == service.CheckpointQRCode {
+			message = "Waiting for the QR code to be scanned in the " + provider.DisplayName + " app"
+		}
+		c.JSON(http.StatusAccepted, models.LinkedInCheckpointResponse{
+			Message:        message,
+			CheckpointType: pending.CheckpointType,
+			ExpiresAt:      pending.ExpiresAt,
+		})
+		return
 	}
-	log.Printf("Response Body: %s", string(body))
 
-	// Parse response
-	var unipileResp UnipileConnectResponse
-	if err := json.Unmarshal(body, &unipileResp); err != nil {
-		log.Printf("ERROR: Failed to parse JSON response: %v", err)
-		log.Printf("Raw response body: %s", string(body))
-		return "", "", fmt.Errorf("failed to parse response: %v", err)
+	h.handleConnectResponse(c, pending.UserID, provider, &service.ConnectResponse{
+		AccountID: pending.AccountID,
+		Name:      account.Name,
+	}, target)
+}
+
+// handleConnectResponse either records a pending checkpoint or, once Unipile
+// has created the account, saves it for the user. When target is set the
+// response belongs to a reconnect and the existing row is updated instead.
+func (h *LinkedInHandler) handleConnectResponse(c *gin.Context, userID uint, provider *service.Provider, resp *service.ConnectResponse, target *models.LinkedAccount) {
+	if resp.IsCheckpoint() {
+		pending := models.PendingConnection{
+			UserID:         userID,
+			Provider:       provider.Name,
+			AccountID:      resp.AccountID,
+			CheckpointType: resp.CheckpointType(),
+			ExpiresAt:      time.Now().Add(checkpointTTL),
+		}
+		if target != nil {
+			pending.LinkedAccountID = &target.ID
+
+			target.Status = models.AccountStatusConnecting
+			if err := h.accounts.WithContext(c.Request.Context()).Update(target); err != nil {
+				slog.ErrorContext(c.Request.Context(), "Failed to update account", "account_id", target.ID, "error", err)
+			}
+		}
+		if err := h.pending.WithContext(c.Request.Context()).Save(&pending); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Failed to save pending connection", "error", err)
+			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save pending connection"})
+			return
+		}
+
+		slog.InfoContext(c.Request.Context(), "Checkpoint required", "checkpoint", pending.CheckpointType, "unipile_account_id", pending.AccountID)
+		c.JSON(http.StatusAccepted, models.LinkedInCheckpointResponse{
+			Message:        provider.DisplayName + " requires additional verification",
+			CheckpointType: pending.CheckpointType,
+			QRCode:         resp.CheckpointQRCode(),
+			ExpiresAt:      pending.ExpiresAt,
+		})
+		return
 	}
-	log.Printf("Parsed response: AccountID=%s, Provider=%s, Name=%s, Title=%s, Detail=%s, Error=%s",
-		unipileResp.AccountID, unipileResp.Provider, unipileResp.Name, unipileResp.Title, unipileResp.Detail, unipileResp.Error)
 
-	// Check for errors in response
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		// Build comprehensive error message from various possible error fields
-		errorMsg := getUnipileErrorMessage(unipileResp)
-		log.Printf("ERROR: Unipile API returned error status: %d, message: %s", resp.StatusCode, errorMsg)
-		return "", "", fmt.Errorf("%s", errorMsg)
+	// Checkpoint responses don't carry the profile name; look it up
+	if resp.DisplayName() == "" {
+		if account, err := h.unipile.GetAccount(c.Request.Context(), resp.AccountID); err == nil {
+			resp.Name = account.Name
+		}
 	}
 
-	// Validate response
-	if unipileResp.AccountID == "" {
-		log.Println("ERROR: Response missing account_id field")
-		return "", "", fmt.Errorf("invalid response from Unipile API: missing account_id")
+	if pending, err := h.pending.WithContext(c.Request.Context()).FindByUserID(userID); err == nil {
+		if err := h.pending.WithContext(c.Request.Context()).Delete(pending); err != nil {
+			slog.ErrorContext(c.Request.Context(), "Failed to delete pending connection", "error", err)
+		}
 	}
 
-	// Return the account ID and name
-	name := unipileResp.Name
-	if name == "" {
-		name = unipileResp.Username
+	if target != nil {
+		h.updateAccount(c, target, resp)
+		return
 	}
 
-	log.Printf("SUCCESS: Returning account_id=%s, name=%s", unipileResp.AccountID, name)
-	log.Println("--- callUnipileAPI END ---")
-	return unipileResp.AccountID, name, nil
+	h.saveAccount(c, userID, provider, resp)
 }
 
-// getUnipileErrorMessage extracts the best error message from Unipile response
-func getUnipileErrorMessage(resp UnipileConnectResponse) string {
-	// Priority order for error messages:
-	// 1. Detail (most descriptive)
-	// 2. Title (error title)
-	// 3. Error (generic error field)
-	// 4. Description (alternative description)
-	// 5. Message (generic message)
-	// 6. Type (error type)
-
-	if resp.Detail != "" {
-		// If we have both title and detail, combine them
-		if resp.Title != "" {
-			return fmt.Sprintf("%s: %s", resp.Title, resp.Detail)
+// updateAccount applies a successful reconnect to the existing linked account
+func (h *LinkedInHandler) updateAccount(c *gin.Context, account *models.LinkedAccount, resp *service.ConnectResponse) {
+	now := time.Now()
+	account.AccountID = resp.AccountID
+	if name := resp.DisplayName(); name != "" {
+		account.AccountName = name
+	}
+	account.Status = models.AccountStatusOK
+	account.LastCheckedAt = &now
+	account.LastError = ""
+	h.applyOwnProfile(c, account)
+
+	if err := h.accounts.WithContext(c.Request.Context()).Update(account); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			c.JSON(http.StatusConflict, models.ErrorResponse{Error: "LinkedIn account is already connected"})
+			return
 		}
-		return resp.Detail
+		slog.ErrorContext(c.Request.Context(), "Failed to update account", "account_id", account.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update account"})
+		return
 	}
 
-	if resp.Title != "" {
-		return resp.Title
-	}
+	c.JSON(http.StatusOK, models.LinkedInConnectResponse{
+		Message:   "LinkedIn account reconnected successfully",
+		AccountID: account.AccountID,
+		Account:   *account,
+	})
+}
 
-	if resp.Error != "" {
-		if resp.Description != "" {
-			return fmt.Sprintf("%s: %s", resp.Error, resp.Description)
+// saveAccount stores the connected account and writes the success response.
+// Connecting an account the user already linked refreshes the existing row,
+// and a previously removed one is restored rather than duplicated.
+func (h *LinkedInHandler) saveAccount(c *gin.Context, userID uint, provider *service.Provider, resp *service.ConnectResponse) {
+	now := time.Now()
+	message := provider.DisplayName + " account connected successfully"
+
+	linkedAccount, err := h.accounts.WithContext(c.Request.Context()).FindByUserIDAndAccountID(userID, provider.Name, resp.AccountID)
+	switch {
+	case err == nil:
+		restoring := linkedAccount.DeletedAt.Valid
+		if name := resp.DisplayName(); name != "" {
+			linkedAccount.AccountName = name
+		}
+		linkedAccount.Status = models.AccountStatusOK
+		linkedAccount.LastCheckedAt = &now
+		linkedAccount.LastError = ""
+		h.applyOwnProfile(c, linkedAccount)
+
+		if restoring {
+			message = provider.DisplayName + " account restored successfully"
+			err = h.accounts.WithContext(c.Request.Context()).Restore(linkedAccount)
+		} else {
+			message = provider.DisplayName + " account was already connected and has been updated"
+			err = h.accounts.WithContext(c.Request.Context()).Update(linkedAccount)
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		linkedAccount = &models.LinkedAccount{
+			UserID:        userID,
+			Provider:      provider.Name,
+			AccountID:     resp.AccountID,
+			AccountName:   resp.DisplayName(),
+			Status:        models.AccountStatusOK,
+			LastCheckedAt: &now,
 		}
-		return resp.Error
+		h.applyOwnProfile(c, linkedAccount)
+		err = h.accounts.WithContext(c.Request.Context()).Create(linkedAccount)
 	}
 
-	if resp.Description != "" {
-		return resp.Description
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: provider.DisplayName + " account is already connected"})
+		return
 	}
-
-	if resp.Message != "" {
-		return resp.Message
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to save linked account", "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to save account"})
+		return
 	}
+	slog.InfoContext(c.Request.Context(), "Saved linked account", "account_id", linkedAccount.ID)
 
-	if resp.Type != "" {
-		return fmt.Sprintf("Error type: %s", resp.Type)
+	c.JSON(http.StatusOK, models.LinkedInConnectResponse{
+		Message:   message,
+		AccountID: linkedAccount.AccountID,
+		Account:   *linkedAccount,
+	})
+}
+
+// applyOwnProfile fills in the profile details of a newly connected LinkedIn
+// account's owner. If Unipile can't return them yet, the account syncer will
+// later.
+func (h *LinkedInHandler) applyOwnProfile(c *gin.Context, account *models.LinkedAccount) {
+	if account.Provider != models.ProviderLinkedIn {
+		return
+	}
+	profile, err := h.unipile.GetOwnProfile(c.Request.Context(), account.AccountID)
+	if err != nil {
+		slog.InfoContext(c.Request.Context(), "Owner profile not available yet", "unipile_account_id", account.AccountID, "error", err)
+		return
 	}
+	service.ApplyOwnProfile(account, profile)
+}
 
-	return "Unknown error from Unipile API"
+// unipileErrorStatus maps a Unipile client error to the HTTP status returned to
+// the caller: rejected input stays a 400, upstream outages become a 502.
+func unipileErrorStatus(err error) int {
+	var apiErr *service.APIError
+	if errors.As(err, &apiErr) {
+		if apiErr.Retryable() {
+			return http.StatusBadGateway
+		}
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
 }
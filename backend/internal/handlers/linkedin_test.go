@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
+)
+
+// fakeUnipile answers connection requests with a canned response and records
+// what it was called with
+type fakeUnipile struct {
+	service.UnipileClient
+
+	resp    *service.ConnectResponse
+	account *service.Account
+	owner   *service.OwnProfile
+	err     error
+
+	cookie, username, password string
+	provider, accountID, code  string
+}
+
+func (f *fakeUnipile) ConnectWithCookie(_ context.Context, cookie string) (*service.ConnectResponse, error) {
+	f.cookie = cookie
+	return f.resp, f.err
+}
+
+func (f *fakeUnipile) ConnectWithCredentials(_ context.Context, username, password string) (*service.ConnectResponse, error) {
+	f.username, f.password = username, password
+	return f.resp, f.err
+}
+
+func (f *fakeUnipile) SolveCheckpoint(_ context.Context, provider, accountID, code string) (*service.ConnectResponse, error) {
+	f.provider, f.accountID, f.code = provider, accountID, code
+	return f.resp, f.err
+}
+
+func (f *fakeUnipile) GetAccount(_ context.Context, accountID string) (*service.Account, error) {
+	f.accountID = accountID
+	if f.account == nil {
+		return nil, errors.New("account not found")
+	}
+	return f.account, f.err
+}
+
+func (f *fakeUnipile) GetOwnProfile(_ context.Context, accountID string) (*service.OwnProfile, error) {
+	if f.owner == nil {
+		return nil, errors.New("profile not synced yet")
+	}
+	return f.owner, nil
+}
+
+func TestLinkedInHandlerConnect(t *testing.T) {
+	connected := &service.ConnectResponse{AccountID: "acc_1", Name: "Jane Doe"}
+	owner := &service.OwnProfile{FirstName: "Jane", LastName: "Doe", PublicIdentifier: "jane-doe", Occupation: "VP of Sales at Acme"}
+
+	tests := []struct {
+		name       string
+		route      string
+		body       string
+		resp       *service.ConnectResponse
+		owner      *service.OwnProfile
+		err        error
+		wantStatus int
+		// wantAccount is the Unipile account ID stored for the user, if any,
+		// and wantHeadline the owner's headline stored with it
+		wantAccount  string
+		wantHeadline string
+		wantFake     fakeUnipile
+	}{
+		{
+			name:        "cookie",
+			route:       "/linkedin/connect/cookie",
+			body:        `{"cookie":"AQEDAR"}`,
+			resp:        connected,
+			wantStatus:  http.StatusOK,
+			wantAccount: "acc_1",
+			wantFake:    fakeUnipile{cookie: "AQEDAR"},
+		},
+		{
+			name:        "credentials",
+			route:       "/linkedin/connect/credentials",
+			body:        `{"username":"jane@example.com","password":"hunter2"}`,
+			resp:        connected,
+			wantStatus:  http.StatusOK,
+			wantAccount: "acc_1",
+			wantFake:    fakeUnipile{username: "jane@example.com", password: "hunter2"},
+		},
+		{
+			name:         "with owner profile",
+			route:        "/linkedin/connect/cookie",
+			body:         `{"cookie":"AQEDAR"}`,
+			resp:         connected,
+			owner:        owner,
+			wantStatus:   http.StatusOK,
+			wantAccount:  "acc_1",
+			wantHeadline: "VP of Sales at Acme",
+			wantFake:     fakeUnipile{cookie: "AQEDAR"},
+		},
+		{
+			name:       "missing cookie",
+			route:      "/linkedin/connect/cookie",
+			body:       `{}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "missing password",
+			route:      "/linkedin/connect/credentials",
+			body:       `{"username":"jane@example.com"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "rejected by Unipile",
+			route:      "/linkedin/connect/credentials",
+			body:       `{"username":"jane@example.com","password":"wrong"}`,
+			err:        &service.APIError{StatusCode: http.StatusUnauthorized, Message: "Invalid credentials"},
+			wantStatus: http.StatusBadRequest,
+			wantFake:   fakeUnipile{username: "jane@example.com", password: "wrong"},
+		},
+		{
+			name:       "Unipile unreachable",
+			route:      "/linkedin/connect/cookie",
+			body:       `{"cookie":"AQEDAR"}`,
+			err:        errors.New("dial tcp: connection refused"),
+			wantStatus: http.StatusBadGateway,
+			wantFake:   fakeUnipile{cookie: "AQEDAR"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.User{}, &models.LinkedAccount{}, &models.PendingConnection{})
+			accounts := repository.NewLinkedAccountRepository(db)
+			unipile := &fakeUnipile{resp: tt.resp, owner: tt.owner, err: tt.err}
+			h := NewLinkedInHandler(unipile, accounts, repository.NewPendingConnectionRepository(db), nil)
+
+			handler := h.ConnectLinkedInWithCookie
+			if tt.route == "/linkedin/connect/credentials" {
+				handler = h.ConnectLinkedInWithCredentials
+			}
+			w := serve(http.MethodPost, tt.route, tt.route, handler, 7, tt.body)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if unipile.cookie != tt.wantFake.cookie || unipile.username != tt.wantFake.username || unipile.password != tt.wantFake.password {
+				t.Errorf("Unipile called with cookie %q, username %q, password %q", unipile.cookie, unipile.username, unipile.password)
+			}
+
+			stored, err := accounts.FindByUserID(7)
+			if err != nil {
+				t.Fatalf("find accounts: %v", err)
+			}
+			switch {
+			case tt.wantAccount == "" && len(stored) > 0:
+				t.Errorf("stored %+v, want no account", stored)
+			case tt.wantAccount != "" && (len(stored) != 1 || stored[0].AccountID != tt.wantAccount || stored[0].AccountName != "Jane Doe"):
+				t.Errorf("stored %+v, want account %s", stored, tt.wantAccount)
+			case tt.wantAccount != "" && stored[0].Headline != tt.wantHeadline:
+				t.Errorf("stored headline %q, want %q", stored[0].Headline, tt.wantHeadline)
+			}
+		})
+	}
+}
+
+func TestLinkedInHandlerSolveCheckpoint(t *testing.T) {
+	approved := &service.Account{ID: "acc_1", Name: "Jane Doe", Sources: []service.AccountSource{{Status: service.AccountStatusOK}}}
+	waiting := &service.Account{ID: "acc_1", Sources: []service.AccountSource{{Status: service.AccountStatusConnecting}}}
+
+	tests := []struct {
+		name    string
+		pending *models.PendingConnection
+		body    string
+		resp    *service.ConnectResponse
+		account *service.Account
+		// wantStatus is the response status; wantAccount the Unipile account
+		// ID stored for the user and wantPending whether the checkpoint is kept
+		wantStatus  int
+		wantAccount string
+		wantPending bool
+		// wantCode is the code sent to Unipile for the provider type wantProvider
+		wantCode     string
+		wantProvider string
+	}{
+		{
+			name:       "no pending connection",
+			body:       `{"code":"123456"}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "expired",
+			pending:    &models.PendingConnection{CheckpointType: service.CheckpointTwoFactor, ExpiresAt: time.Now().Add(-time.Minute)},
+			body:       `{"code":"123456"}`,
+			wantStatus: http.StatusGone,
+		},
+		{
+			name:        "code missing",
+			pending:     &models.PendingConnection{CheckpointType: service.CheckpointTwoFactor},
+			body:        `{}`,
+			wantStatus:  http.StatusBadRequest,
+			wantPending: true,
+		},
+		{
+			name:         "code accepted",
+			pending:      &models.PendingConnection{CheckpointType: service.CheckpointTwoFactor},
+			body:         `{"code":"123456"}`,
+			resp:         &service.ConnectResponse{AccountID: "acc_1", Name: "Jane Doe"},
+			wantStatus:   http.StatusOK,
+			wantAccount:  "acc_1",
+			wantCode:     "123456",
+			wantProvider: "LINKEDIN",
+		},
+		{
+			name:         "code for another provider",
+			pending:      &models.PendingConnection{Provider: models.ProviderInstagram, CheckpointType: service.CheckpointTwoFactor},
+			body:         `{"code":"123456"}`,
+			resp:         &service.ConnectResponse{AccountID: "acc_1", Name: "Jane Doe"},
+			wantStatus:   http.StatusOK,
+			wantAccount:  "acc_1",
+			wantCode:     "123456",
+			wantProvider: "INSTAGRAM",
+		},
+		{
+			name:    "another checkpoint",
+			pending: &models.PendingConnection{CheckpointType: service.CheckpointTwoFactor},
+			body:    `{"code":"123456"}`,
+			resp: &service.ConnectResponse{
+				Object:     "Checkpoint",
+				AccountID:  "acc_1",
+				Checkpoint: &service.Checkpoint{Type: service.CheckpointOTP},
+			},
+			wantStatus:   http.StatusAccepted,
+			wantPending:  true,
+			wantCode:     "123456",
+			wantProvider: "LINKEDIN",
+		},
+		{
+			name:        "in-app validation pending",
+			pending:     &models.PendingConnection{CheckpointType: service.CheckpointInAppValidation},
+			body:        `{}`,
+			account:     waiting,
+			wantStatus:  http.StatusAccepted,
+			wantPending: true,
+		},
+		{
+			name:        "in-app validation approved",
+			pending:     &models.PendingConnection{CheckpointType: service.CheckpointInAppValidation},
+			body:        `{}`,
+			account:     approved,
+			wantStatus:  http.StatusOK,
+			wantAccount: "acc_1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.User{}, &models.LinkedAccount{}, &models.PendingConnection{})
+			accounts := repository.NewLinkedAccountRepository(db)
+			pending := repository.NewPendingConnectionRepository(db)
+			if tt.pending != nil {
+				tt.pending.UserID = 7
+				tt.pending.AccountID = "acc_1"
+				if tt.pending.ExpiresAt.IsZero() {
+					tt.pending.ExpiresAt = time.Now().Add(checkpointTTL)
+				}
+				if err := pending.Save(tt.pending); err != nil {
+					t.Fatalf("save pending connection: %v", err)
+				}
+			}
+			unipile := &fakeUnipile{resp: tt.resp, account: tt.account}
+			h := NewLinkedInHandler(unipile, accounts, pending, nil)
+
+			route := "/linkedin/connect/checkpoint"
+			w := serve(http.MethodPost, route, route, h.SolveCheckpoint, 7, tt.body)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if unipile.code != tt.wantCode || unipile.provider != tt.wantProvider {
+				t.Errorf("Unipile called with code %q for %q, want %q for %q", unipile.code, unipile.provider, tt.wantCode, tt.wantProvider)
+			}
+
+			stored, err := accounts.FindByUserID(7)
+			if err != nil {
+				t.Fatalf("find accounts: %v", err)
+			}
+			switch {
+			case tt.wantAccount == "" && len(stored) > 0:
+				t.Errorf("stored %+v, want no account", stored)
+			case tt.wantAccount != "" && (len(stored) != 1 || stored[0].AccountID != tt.wantAccount || stored[0].AccountName != "Jane Doe"):
+				t.Errorf("stored %+v, want account %s", stored, tt.wantAccount)
+			}
+
+			_, err = pending.FindByUserID(7)
+			if kept := err == nil; kept != tt.wantPending {
+				t.Errorf("pending connection kept = %v, want %v", kept, tt.wantPending)
+			}
+		})
+	}
+}
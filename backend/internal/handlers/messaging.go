@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
+)
+
+const (
+	defaultPageSize = 25
+	maxPageSize     = 100
+)
+
+// MessagingHandler handles inbox requests for linked accounts
+type MessagingHandler struct {
+	unipile   service.UnipileClient
+	accounts  *repository.LinkedAccountRepository
+	templates *repository.TemplateRepository
+	profiles  *service.ProfileService
+	queue     *service.ActionQueue
+}
+
+// NewMessagingHandler creates a new messaging handler
+func NewMessagingHandler(unipile service.UnipileClient, accounts *repository.LinkedAccountRepository, templates *repository.TemplateRepository, profiles *service.ProfileService, queue *service.ActionQueue) *MessagingHandler {
+	return &MessagingHandler{
+		unipile:   unipile,
+		accounts:  accounts,
+		templates: templates,
+		profiles:  profiles,
+		queue:     queue,
+	}
+}
+
+// ListChats lists the chats of one of the user's linked accounts. Supports
+// cursor pagination (cursor, limit) and unread=true to only return unread chats.
+func (h *MessagingHandler) ListChats(c *gin.Context) {
+	account, ok := findOwnedAccount(c, h.accounts)
+	if !ok {
+		return
+	}
+
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+	unread, _ := strconv.ParseBool(c.Query("unread"))
+
+	list, err := h.unipile.ListChats(c.Request.Context(), service.ListChatsParams{
+		AccountID: account.AccountID,
+		Cursor:    c.Query("cursor"),
+		Limit:     limit,
+		Unread:    unread,
+	})
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to list chats", "account_id", account.ID, "error", err)
+		c.JSON(unipileErrorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	chats := make([]models.Chat, 0, len(list.Items))
+	for i := range list.Items {
+		chats = append(chats, list.Items[i].ToModel(account.ID))
+	}
+
+	c.JSON(http.StatusOK, models.ChatListResponse{
+		Chats:      chats,
+		NextCursor: list.Cursor,
+		Count:      len(chats),
+	})
+}
+
+// pageSize reads the limit query parameter, defaulting to defaultPageSize and
+// capped at maxPageSize. On invalid input it writes a 400 and returns false.
+func pageSize(c *gin.Context) (int, bool) {
+	value := c.Query("limit")
+	if value == "" {
+		return defaultPageSize, true
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "limit must be a positive integer"})
+		return 0, false
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	return limit, true
+}
+
+// ListMessages lists the messages of a chat, newest first, with cursor
+// pagination (cursor, limit)
+func (h *MessagingHandler) ListMessages(c *gin.Context) {
+	account, ok := findOwnedAccount(c, h.accounts)
+	if !ok {
+		return
+	}
+	chat, ok := h.findAccountChat(c, account)
+	if !ok {
+		return
+	}
+	chatID := chat.ID
+
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.unipile.ListMessages(c.Request.Context(), chatID, c.Query("cursor"), limit)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to list messages", "chat_id", chatID, "error", err)
+		c.JSON(unipileErrorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	messages := make([]models.Message, 0, len(list.Items))
+	for i := range list.Items {
+		messages = append(messages, list.Items[i].ToModel(account.ID))
+	}
+
+	c.JSON(http.StatusOK, models.MessageListResponse{
+		Messages:   messages,
+		NextCursor: list.Cursor,
+		Count:      len(messages),
+	})
+}
+
+// SendMessage queues a text message in an existing chat. With a template_id
+// the text is rendered from the other attendee's profile.
+func (h *MessagingHandler) SendMessage(c *gin.Context) {
+	account, ok := findOwnedAccount(c, h.accounts)
+	if !ok {
+		return
+	}
+
+	var req models.SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	chat, ok := h.findAccountChat(c, account)
+	if !ok {
+		return
+	}
+
+	text := req.Text
+	if req.TemplateID != 0 {
+		if chat.AttendeeProviderID == "" {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Templates can only be used in one-to-one chats"})
+			return
+		}
+		if text, ok = h.renderTemplate(c, account, req.TemplateID, chat.AttendeeProviderID); !ok {
+			return
+		}
+	}
+
+	enqueueAction(c, h.queue, &models.QueuedAction{
+		UserID:          account.UserID,
+		LinkedAccountID: account.ID,
+		Type:            models.QueuedActionSendMessage,
+		Target:          chat.ID,
+		Payload:         models.ActionPayload{Text: text},
+	}, req.SendAt, "Message queued")
+}
+
+// StartChat queues a new chat with a LinkedIn member, started by sending the
+// first message, given as text or rendered from a template
+func (h *MessagingHandler) StartChat(c *gin.Context) {
+	account, ok := findOwnedAccount(c, h.accounts)
+	if !ok {
+		return
+	}
+
+	var req models.StartChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	text := req.Text
+	if req.TemplateID != 0 {
+		if text, ok = h.renderTemplate(c, account, req.TemplateID, req.AttendeeProviderID); !ok {
+			return
+		}
+	}
+
+	enqueueAction(c, h.queue, &models.QueuedAction{
+		UserID:          account.UserID,
+		LinkedAccountID: account.ID,
+		Type:            models.QueuedActionStartChat,
+		Target:          req.AttendeeProviderID,
+		Payload:         models.ActionPayload{Text: text},
+	}, req.SendAt, "Chat queued")
+}
+
+// renderTemplate renders a template for the member with the given provider
+// ID, whose profile is looked up through the profile cache. On failure it
+// writes the error response and returns false.
+func (h *MessagingHandler) renderTemplate(c *gin.Context, account *models.LinkedAccount, templateID uint, providerID string) (string, bool) {
+	tmpl, ok := findTemplate(c, h.templates, templateID)
+	if !ok {
+		return "", false
+	}
+	profile, ok := lookupProfile(c, h.profiles, account, providerID)
+	if !ok {
+		return "", false
+	}
+	return renderForRecipient(c, tmpl, profile)
+}
+
+// findAccountChat checks that the :chatId path parameter names a chat of the
+// given account. On failure it writes the error response and returns false.
+func (h *MessagingHandler) findAccountChat(c *gin.Context, account *models.LinkedAccount) (*service.Chat, bool) {
+	chat, err := h.unipile.GetChat(c.Request.Context(), c.Param("chatId"))
+	if err != nil {
+		var apiErr *service.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Chat not found"})
+			return nil, false
+		}
+		c.JSON(unipileErrorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return nil, false
+	}
+
+	if chat.AccountID != account.AccountID {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Chat not found"})
+		return nil, false
+	}
+
+	return chat, true
+}
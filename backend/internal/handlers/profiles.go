@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
+)
+
+// ProfilesHandler handles LinkedIn profile lookups
+type ProfilesHandler struct {
+	accounts *repository.LinkedAccountRepository
+	profiles *service.ProfileService
+}
+
+// NewProfilesHandler creates a new profiles handler
+func NewProfilesHandler(accounts *repository.LinkedAccountRepository, profiles *service.ProfileService) *ProfilesHandler {
+	return &ProfilesHandler{
+		accounts: accounts,
+		profiles: profiles,
+	}
+}
+
+// GetProfile returns a LinkedIn profile as seen by a linked account. The
+// identifier is a provider ID or public identifier. Cached profiles are
+// returned unless refresh=true.
+func (h *ProfilesHandler) GetProfile(c *gin.Context) {
+	account, ok := findOwnedAccount(c, h.accounts)
+	if !ok {
+		return
+	}
+
+	identifier, ok := service.ParseProfileIdentifier(c.Param("identifier"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid profile identifier"})
+		return
+	}
+
+	cached, hit, ok := getProfile(c, h.profiles, account, identifier, c.Query("refresh") == "true")
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ProfileResponse{
+		Profile:   cached.Profile,
+		Cached:    hit,
+		FetchedAt: cached.FetchedAt,
+	})
+}
+
+// lookupProfile fetches a LinkedIn profile by provider ID or public
+// identifier as seen by the account, from the cache when possible. On failure
+// it writes the error response and returns false.
+func lookupProfile(c *gin.Context, profiles *service.ProfileService, account *models.LinkedAccount, identifier string) (*models.Profile, bool) {
+	cached, _, ok := getProfile(c, profiles, account, identifier, false)
+	if !ok {
+		return nil, false
+	}
+	return &cached.Profile, true
+}
+
+func getProfile(c *gin.Context, profiles *service.ProfileService, account *models.LinkedAccount, identifier string, refresh bool) (*models.CachedProfile, bool, bool) {
+	cached, hit, err := profiles.Get(c.Request.Context(), account, identifier, refresh)
+	if err != nil {
+		var exceeded *service.QuotaExceededError
+		if errors.As(err, &exceeded) {
+			writeQuotaExceeded(c, exceeded)
+			return nil, false, false
+		}
+		var apiErr *service.APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Profile not found"})
+			return nil, false, false
+		}
+		slog.ErrorContext(c.Request.Context(), "Failed to fetch profile", "identifier", identifier, "account_id", account.ID, "error", err)
+		c.JSON(unipileErrorStatus(err), models.ErrorResponse{Error: err.Error()})
+		return nil, false, false
+	}
+
+	return cached, hit, true
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
+)
+
+// QueueHandler lets users inspect and cancel their queued outbound actions
+type QueueHandler struct {
+	actions *repository.QueueRepository
+}
+
+// NewQueueHandler creates a new queue handler
+func NewQueueHandler(actions *repository.QueueRepository) *QueueHandler {
+	return &QueueHandler{actions: actions}
+}
+
+// ListQueue lists the user's queued actions, next to run first. Supports
+// account_id, status and limit query parameters.
+func (h *QueueHandler) ListQueue(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	accountID, ok := accountIDFilter(c)
+	if !ok {
+		return
+	}
+
+	status := strings.ToUpper(c.Query("status"))
+	switch status {
+	case "", models.QueueStatusPending, models.QueueStatusRunning, models.QueueStatusDone,
+		models.QueueStatusFailed, models.QueueStatusCancelled:
+	default:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "status must be PENDING, RUNNING, DONE, FAILED or CANCELLED"})
+		return
+	}
+
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+
+	actions, err := h.actions.WithContext(c.Request.Context()).FindByUserID(userID, accountID, status, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch queue"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.QueueListResponse{
+		Actions: actions,
+		Count:   len(actions),
+	})
+}
+
+// CancelAction cancels one pending action
+func (h *QueueHandler) CancelAction(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid action ID"})
+		return
+	}
+
+	action, err := h.actions.WithContext(c.Request.Context()).FindByUserIDAndID(userID, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Action not found"})
+		return
+	}
+
+	cancelled, err := h.actions.WithContext(c.Request.Context()).Cancel(action, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to cancel action"})
+		return
+	}
+	if !cancelled {
+		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "Only pending actions can be cancelled"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.QueuedActionResponse{
+		Message: "Action cancelled",
+		Action:  *action,
+	})
+}
+
+// CancelQueue cancels all of the user's pending actions, or only those of one
+// linked account when account_id is given
+func (h *QueueHandler) CancelQueue(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	accountID, ok := accountIDFilter(c)
+	if !ok {
+		return
+	}
+
+	cancelled, err := h.actions.WithContext(c.Request.Context()).CancelPending(userID, accountID, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to cancel actions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Pending actions cancelled",
+		"cancelled": cancelled,
+	})
+}
+
+// enqueueAction queues an outbound action, delayed until sendAt if given, and
+// writes the 202 response
+func enqueueAction(c *gin.Context, queue *service.ActionQueue, action *models.QueuedAction, sendAt *time.Time, message string) {
+	if !checkQuota(c, queue, action.LinkedAccountID, action.Type, sendAt) {
+		return
+	}
+	if sendAt != nil {
+		action.ScheduledAt = *sendAt
+	}
+
+	if err := queue.Enqueue(c.Request.Context(), action); err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to queue action", "type", action.Type, "account_id", action.LinkedAccountID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to queue action"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.QueuedActionResponse{
+		Message: message,
+		Action:  *action,
+	})
+}
+
+// checkQuota refuses actions of a linked account whose quota is used up
+// until after sendAt, or now if unset, so they aren't accepted only to wait
+// for the quota. On failure it writes the 429 or error response and returns
+// false.
+func checkQuota(c *gin.Context, queue *service.ActionQueue, linkedAccountID uint, actionType string, sendAt *time.Time) bool {
+	err := queue.CheckQuota(c.Request.Context(), linkedAccountID, actionType)
+	var exceeded *service.QuotaExceededError
+	switch {
+	case errors.As(err, &exceeded):
+		if sendAt != nil && !sendAt.Before(exceeded.ResetAt) {
+			return true
+		}
+		writeQuotaExceeded(c, exceeded)
+		return false
+	case err != nil:
+		slog.ErrorContext(c.Request.Context(), "Failed to check quota", "account_id", linkedAccountID, "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to queue action"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
+)
+
+// QuotaHandler reports the action quotas of linked accounts
+type QuotaHandler struct {
+	accounts *repository.LinkedAccountRepository
+	quotas   *service.QuotaService
+}
+
+// NewQuotaHandler creates a new quota handler
+func NewQuotaHandler(accounts *repository.LinkedAccountRepository, quotas *service.QuotaService) *QuotaHandler {
+	return &QuotaHandler{
+		accounts: accounts,
+		quotas:   quotas,
+	}
+}
+
+// GetQuota returns the daily and weekly usage of every action quota of a
+// linked account
+func (h *QuotaHandler) GetQuota(c *gin.Context) {
+	account, ok := findOwnedAccount(c, h.accounts)
+	if !ok {
+		return
+	}
+
+	quotas, err := h.quotas.Usage(c.Request.Context(), account.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch quota usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.QuotaResponse{
+		AccountID: account.ID,
+		Quotas:    quotas,
+	})
+}
+
+// writeQuotaExceeded writes a 429 with the time the quota resets
+func writeQuotaExceeded(c *gin.Context, exceeded *service.QuotaExceededError) {
+	retryAfter := math.Ceil(time.Until(exceeded.ResetAt).Seconds())
+	c.Header("Retry-After", strconv.Itoa(int(math.Max(retryAfter, 1))))
+	c.JSON(http.StatusTooManyRequests, models.QuotaExceededResponse{
+		Error:   exceeded.Error(),
+		Action:  exceeded.Action,
+		Window:  exceeded.Window,
+		Limit:   exceeded.Limit,
+		Used:    exceeded.Used,
+		ResetAt: exceeded.ResetAt,
+	})
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+)
+
+// SearchHandler handles searches over the local message store
+type SearchHandler struct {
+	messages *repository.MessageRepository
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(messages *repository.MessageRepository) *SearchHandler {
+	return &SearchHandler{messages: messages}
+}
+
+// SearchMessages searches the synced messages of the user's linked accounts.
+// q is required; account_id restricts the search to one linked account.
+func (h *SearchHandler) SearchMessages(c *gin.Context) {
+	userID := c.GetUint("user_id")
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Query parameter q is required"})
+		return
+	}
+
+	accountID, ok := accountIDFilter(c)
+	if !ok {
+		return
+	}
+
+	limit, ok := pageSize(c)
+	if !ok {
+		return
+	}
+
+	results, err := h.messages.WithContext(c.Request.Context()).Search(userID, accountID, query, limit)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Message search failed", "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to search messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MessageSearchResponse{
+		Query:   query,
+		Results: results,
+		Count:   len(results),
+	})
+}
+
+// accountIDFilter reads the optional account_id query parameter, 0 when
+// absent. On invalid input it writes a 400 and returns false.
+func accountIDFilter(c *gin.Context) (uint, bool) {
+	value := c.Query("account_id")
+	if value == "" {
+		return 0, true
+	}
+
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid account ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
+)
+
+// TemplatesHandler handles message template requests
+type TemplatesHandler struct {
+	templates *repository.TemplateRepository
+}
+
+// NewTemplatesHandler creates a new templates handler
+func NewTemplatesHandler(templates *repository.TemplateRepository) *TemplatesHandler {
+	return &TemplatesHandler{templates: templates}
+}
+
+// ListTemplates lists the user's templates
+func (h *TemplatesHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.templates.WithContext(c.Request.Context()).FindByUserID(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TemplateListResponse{
+		Templates: templates,
+		Count:     len(templates),
+	})
+}
+
+// CreateTemplate creates a template after checking its syntax and variables
+func (h *TemplatesHandler) CreateTemplate(c *gin.Context) {
+	var req models.TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	parsed, err := service.ParseMessageTemplate(req.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	tmpl := &models.MessageTemplate{
+		UserID:    c.GetUint("user_id"),
+		Name:      strings.TrimSpace(req.Name),
+		Body:      req.Body,
+		Variables: parsed.Variables,
+	}
+	if err := h.templates.WithContext(c.Request.Context()).Create(tmpl); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create template"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, tmpl)
+}
+
+// GetTemplate returns one template
+func (h *TemplatesHandler) GetTemplate(c *gin.Context) {
+	tmpl, ok := h.findOwnedTemplate(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// UpdateTemplate replaces a template's name and body
+func (h *TemplatesHandler) UpdateTemplate(c *gin.Context) {
+	tmpl, ok := h.findOwnedTemplate(c)
+	if !ok {
+		return
+	}
+
+	var req models.TemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	parsed, err := service.ParseMessageTemplate(req.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	tmpl.Name = strings.TrimSpace(req.Name)
+	tmpl.Body = req.Body
+	tmpl.Variables = parsed.Variables
+	if err := h.templates.WithContext(c.Request.Context()).Update(tmpl); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// DeleteTemplate deletes a template. Messages already queued from it are
+// unaffected, since they hold the rendered text.
+func (h *TemplatesHandler) DeleteTemplate(c *gin.Context) {
+	tmpl, ok := h.findOwnedTemplate(c)
+	if !ok {
+		return
+	}
+
+	if err := h.templates.WithContext(c.Request.Context()).Delete(tmpl); err != nil {
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete template"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
+}
+
+// PreviewTemplate renders a template against the sample profile, or against
+// the profile variables given in the request body, and reports variables the
+// profile has no value for
+func (h *TemplatesHandler) PreviewTemplate(c *gin.Context) {
+	tmpl, ok := h.findOwnedTemplate(c)
+	if !ok {
+		return
+	}
+
+	var req models.TemplatePreviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+	profile := service.SampleTemplateData
+	if req.Profile != nil {
+		profile = *req.Profile
+	}
+
+	parsed, err := service.ParseMessageTemplate(tmpl.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	resp := models.TemplatePreviewResponse{
+		Variables: parsed.Variables,
+		Profile:   profile,
+	}
+	resp.Rendered, err = parsed.Render(profile)
+	var missing *service.MissingVariablesError
+	switch {
+	case errors.As(err, &missing):
+		// Show what would be sent anyway, with the gaps left empty
+		resp.MissingVariables = missing.Variables
+		resp.Rendered, err = parsed.RenderPartial(profile)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+			return
+		}
+	case err != nil:
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// findOwnedTemplate loads the template named by the :id path parameter,
+// checking it belongs to the authenticated user. On failure it writes the
+// error response and returns false.
+func (h *TemplatesHandler) findOwnedTemplate(c *gin.Context) (*models.MessageTemplate, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid template ID"})
+		return nil, false
+	}
+
+	tmpl, err := h.templates.WithContext(c.Request.Context()).FindByUserIDAndID(c.GetUint("user_id"), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Template not found"})
+		return nil, false
+	}
+	return tmpl, true
+}
+
+// findTemplate loads and parses one of the user's templates. It is called
+// before fetching the recipient's profile, so an unknown template costs no
+// profile view. On failure it writes the error response and returns false.
+func findTemplate(c *gin.Context, templates *repository.TemplateRepository, templateID uint) (*service.MessageTemplate, bool) {
+	tmpl, err := templates.WithContext(c.Request.Context()).FindByUserIDAndID(c.GetUint("user_id"), templateID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Template not found"})
+		return nil, false
+	}
+
+	parsed, err := service.ParseMessageTemplate(tmpl.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return nil, false
+	}
+	return parsed, true
+}
+
+// renderForRecipient renders a template for a LinkedIn member. If the
+// profile lacks a variable the template needs, it writes the error response
+// and returns false, so nothing is sent.
+func renderForRecipient(c *gin.Context, tmpl *service.MessageTemplate, profile *models.Profile) (string, bool) {
+	text, err := tmpl.Render(service.NewTemplateData(profile))
+	if err != nil {
+		var missing *service.MissingVariablesError
+		if errors.As(err, &missing) {
+			c.JSON(http.StatusBadRequest, models.TemplateErrorResponse{
+				Error:            "The recipient's profile has no value for some template variables",
+				MissingVariables: missing.Variables,
+			})
+			return "", false
+		}
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+		return "", false
+	}
+	if text == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Template rendered an empty message"})
+		return "", false
+	}
+
+	return text, true
+}
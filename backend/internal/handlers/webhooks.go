@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"github.com/johnson7543/chatsheet-assessment/internal/service"
+)
+
+const (
+	// webhookAuthHeader is the custom header configured on Unipile webhooks
+	webhookAuthHeader = "Unipile-Auth"
+
+	// maxWebhookBodySize caps how much of a webhook body is read
+	maxWebhookBodySize = 1 << 20
+)
+
+// WebhookHandler receives webhooks from Unipile
+type WebhookHandler struct {
+	secret    string
+	events    *repository.WebhookEventRepository
+	processor *service.WebhookProcessor
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(secret string, events *repository.WebhookEventRepository, processor *service.WebhookProcessor) *WebhookHandler {
+	return &WebhookHandler{
+		secret:    secret,
+		events:    events,
+		processor: processor,
+	}
+}
+
+// HandleUnipile verifies and stores an account status or messaging webhook,
+// then hands it to the processor. Redelivered events are acknowledged without
+// being stored again.
+func (h *WebhookHandler) HandleUnipile(c *gin.Context) {
+	provided := c.GetHeader(webhookAuthHeader)
+	if h.secret == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(h.secret)) != 1 {
+		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "Invalid webhook signature"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodySize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Failed to read body"})
+		return
+	}
+
+	payload, err := service.ParseWebhook(body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid webhook payload"})
+		return
+	}
+
+	event := models.WebhookEvent{
+		EventID:   payload.ID(body, time.Now()),
+		Type:      payload.Type(),
+		AccountID: payload.UnipileAccountID(),
+		Payload:   string(body),
+	}
+	if event.Type == "" || event.AccountID == "" {
+		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Unrecognized webhook payload"})
+		return
+	}
+
+	created, err := h.events.WithContext(c.Request.Context()).CreateIfNew(&event)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Failed to store webhook event", "error", err)
+		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to store event"})
+		return
+	}
+
+	if !created {
+		c.JSON(http.StatusOK, gin.H{"message": "Duplicate event ignored"})
+		return
+	}
+
+	h.processor.Enqueue(event.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Event received"})
+}
@@ -0,0 +1,52 @@
+// Package logging sets up the application's structured logger, which scrubs
+// secrets from everything it writes.
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/config"
+)
+
+// Setup makes a redacting JSON slog logger the default. Records logged with a
+// request's context carry its request ID. The standard log package writes
+// through the logger too, so libraries that log that way are scrubbed as well.
+// Debug records, such as Unipile request and response bodies, are only written
+// when the configured level is debug.
+func Setup(cfg config.LogConfig) {
+	level := slog.LevelInfo
+	if cfg.Level != "" {
+		if err := level.UnmarshalText([]byte(cfg.Level)); err != nil {
+			defer slog.Warn("Unknown log level, using info", "level", cfg.Level)
+		}
+	}
+
+	handler := slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	})
+	slog.SetDefault(slog.New(contextHandler{handler}))
+}
+
+// redactAttr is the redaction layer: secret attributes are dropped to a
+// placeholder, and secret fields are scrubbed from messages and other values
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if IsSecret(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(RedactString(a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case http.Header:
+			a.Value = slog.AnyValue(RedactHeaders(v))
+		case error:
+			a.Value = slog.StringValue(RedactString(v.Error()))
+		}
+	}
+	return a
+}
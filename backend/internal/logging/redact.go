@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces secret values in logs
+const Redacted = "[REDACTED]"
+
+// secretKeys are field, header and parameter names whose values are never
+// logged. Names containing "password" (imap_password, ...) are secret too.
+var secretKeys = []string{"password", "access_token", "refresh_token", "cookie", "li_at", "x-api-key", "authorization"}
+
+// exactSecretKeys are secret only as the whole name, such as the "code" of a
+// checkpoint, which would otherwise catch status_code
+var exactSecretKeys = []string{"code"}
+
+var (
+	// secretKey matches any secret name, as part of a longer name too for
+	// secretKeys
+	secretKey = `(?:[\w-]*(?:` + strings.Join(secretKeys, "|") + `)[\w-]*|` + strings.Join(exactSecretKeys, "|") + `)`
+
+	// secretJSONField matches "key": "value" pairs in JSON bodies
+	secretJSONField = regexp.MustCompile(`(?i)("` + secretKey + `"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+	// secretParam matches key=value and key: value pairs in headers, cookies,
+	// query strings and messages, including Go's map[Key:[value]] formatting
+	secretParam = regexp.MustCompile(`(?i)((?:^|[^\w-])` + secretKey + `(?::\[|\s*[:=]\s*))(?:bearer\s+)?[^\s,;&"\]]+`)
+)
+
+// IsSecret reports whether values named key must not be logged
+func IsSecret(key string) bool {
+	key = strings.ToLower(key)
+	for _, secret := range exactSecretKeys {
+		if key == secret {
+			return true
+		}
+	}
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString scrubs the values of secret fields from s, which may be a JSON
+// body, a header or query string, or a free-form message
+func RedactString(s string) string {
+	s = secretJSONField.ReplaceAllString(s, `$1"`+Redacted+`"`)
+	return secretParam.ReplaceAllString(s, "${1}"+Redacted)
+}
+
+// RedactHeaders returns a copy of h with the values of secret headers
+// replaced
+func RedactHeaders(h http.Header) http.Header {
+	redacted := h.Clone()
+	for key := range redacted {
+		if IsSecret(key) {
+			redacted[key] = []string{Redacted}
+		}
+	}
+	return redacted
+}
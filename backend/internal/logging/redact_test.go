@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestRedactString(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "JSON password",
+			in:   `{"username":"jane@example.com","password":"hunter2"}`,
+			want: `{"username":"jane@example.com","password":"[REDACTED]"}`,
+		},
+		{
+			name: "JSON names containing a secret",
+			in:   `{"imap_password": "p\"w", "refresh_token":"abc"}`,
+			want: `{"imap_password": "[REDACTED]", "refresh_token":"[REDACTED]"}`,
+		},
+		{
+			name: "checkpoint code",
+			in:   `{"account_id":"acc_1","code":"123456"}`,
+			want: `{"account_id":"acc_1","code":"[REDACTED]"}`,
+		},
+		{
+			name: "status code is kept",
+			in:   `{"status_code":"500","error_code":"E1"} status_code=500`,
+			want: `{"status_code":"500","error_code":"E1"} status_code=500`,
+		},
+		{
+			name: "query string",
+			in:   "/callback?code=123456&access_token=abc&state=ok",
+			want: "/callback?code=[REDACTED]&access_token=[REDACTED]&state=ok",
+		},
+		{
+			name: "header map",
+			in:   "map[Authorization:[Bearer abc] X-Api-Key:[k] Accept:[*/*]]",
+			want: "map[Authorization:[[REDACTED]] X-Api-Key:[[REDACTED]] Accept:[*/*]]",
+		},
+		{
+			name: "cookie",
+			in:   "cookie: li_at=AQEDAR; JSESSIONID=x",
+			want: "cookie: [REDACTED]; JSESSIONID=x",
+		},
+		{
+			name: "no secrets",
+			in:   "connected account acc_1 for user 42",
+			want: "connected account acc_1 for user 42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RedactString(tt.in); got != tt.want {
+				t.Errorf("RedactString(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSecret(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"password", true},
+		{"IMAP_PASSWORD", true},
+		{"Authorization", true},
+		{"X-API-KEY", true},
+		{"code", true},
+		{"Code", true},
+		{"status_code", false},
+		{"account_id", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsSecret(tt.key); got != tt.want {
+			t.Errorf("IsSecret(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	h := http.Header{"Authorization": {"Bearer abc"}, "Accept": {"*/*"}}
+	want := http.Header{"Authorization": {Redacted}, "Accept": {"*/*"}}
+	if got := RedactHeaders(h); !reflect.DeepEqual(got, want) {
+		t.Errorf("RedactHeaders = %v, want %v", got, want)
+	}
+	if h.Get("Authorization") != "Bearer abc" {
+		t.Errorf("RedactHeaders changed its argument")
+	}
+}
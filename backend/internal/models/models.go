@@ -19,21 +19,421 @@ type User struct {
 	LinkedAccounts []LinkedAccount `gorm:"foreignKey:UserID" json:"linked_accounts,omitempty"`
 }
 
-// LinkedAccount represents a connected social media account
+// Providers accounts can be connected to, stored in LinkedAccount.Provider
+const (
+	ProviderLinkedIn  = "linkedin"
+	ProviderWhatsApp  = "whatsapp"
+	ProviderInstagram = "instagram"
+	ProviderTelegram  = "telegram"
+	ProviderGmail     = "gmail"
+	ProviderOutlook   = "outlook"
+)
+
+// Linked account health statuses, mirroring Unipile's source statuses
+const (
+	AccountStatusOK           = "OK"
+	AccountStatusCredentials  = "CREDENTIALS"
+	AccountStatusDisconnected = "DISCONNECTED"
+	AccountStatusConnecting   = "CONNECTING"
+	AccountStatusError        = "ERROR"
+
+	// AccountStatusPendingDeletion marks an account whose Unipile deletion
+	// failed; the syncer retries it and then removes the local row
+	AccountStatusPendingDeletion = "PENDING_DELETION"
+)
+
+// LinkedAccount represents a connected social media account. A user can link
+// each provider account only once; soft-deleted rows don't count.
 type LinkedAccount struct {
-	ID          uint           `gorm:"primaryKey" json:"id"`
-	UserID      uint           `gorm:"not null;index" json:"user_id"`
-	Provider    string         `gorm:"not null;default:'linkedin'" json:"provider"`
-	AccountID   string         `gorm:"not null" json:"account_id"`
-	AccountName string         `json:"account_name,omitempty"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `gorm:"index" json:"-"`
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	UserID        uint           `gorm:"not null;index;uniqueIndex:idx_linked_accounts_user_provider_account,where:deleted_at IS NULL" json:"user_id"`
+	Provider      string         `gorm:"not null;default:'linkedin';uniqueIndex:idx_linked_accounts_user_provider_account" json:"provider"`
+	AccountID     string         `gorm:"not null;uniqueIndex:idx_linked_accounts_user_provider_account" json:"account_id"`
+	AccountName   string         `json:"account_name,omitempty"`
+	Status        string         `gorm:"not null;default:'OK';index" json:"status"`
+	LastCheckedAt *time.Time     `json:"last_checked_at,omitempty"`
+	LastError     string         `json:"last_error,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Queued actions only run within the account's working hours, "HH:MM" in
+	// its IANA timezone, on its working days (e.g. "Mon,Tue,Wed,Thu,Fri")
+	Timezone          string `gorm:"not null;default:'UTC'" json:"timezone"`
+	WorkingHoursStart string `gorm:"not null;default:'09:00'" json:"working_hours_start"`
+	WorkingHoursEnd   string `gorm:"not null;default:'18:00'" json:"working_hours_end"`
+	WorkingDays       string `gorm:"not null;default:'Mon,Tue,Wed,Thu,Fri'" json:"working_days"`
+
+	// The owner's own LinkedIn profile, refreshed on every status sync
+	PublicIdentifier string     `json:"public_identifier,omitempty"`
+	ProfileURL       string     `json:"profile_url,omitempty"`
+	Headline         string     `json:"headline,omitempty"`
+	AvatarURL        string     `json:"avatar_url,omitempty"`
+	Premium          bool       `gorm:"not null;default:false" json:"premium"`
+	SalesNavigator   bool       `gorm:"not null;default:false" json:"sales_navigator"`
+	ProfileSyncedAt  *time.Time `json:"profile_synced_at,omitempty"`
 
 	// Relationships
 	User User `gorm:"foreignKey:UserID" json:"-"`
 }
 
+// PendingConnection tracks a LinkedIn connection waiting on a checkpoint
+// (2FA, OTP, captcha or in-app validation). Each user has at most one.
+// LinkedAccountID is set when the checkpoint came from a reconnect.
+type PendingConnection struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	UserID          uint      `gorm:"not null;uniqueIndex" json:"user_id"`
+	LinkedAccountID *uint     `json:"linked_account_id,omitempty"`
+	Provider        string    `gorm:"not null;default:'linkedin'" json:"provider"`
+	AccountID       string    `gorm:"not null" json:"account_id"`
+	CheckpointType  string    `json:"checkpoint_type"`
+	ExpiresAt       time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// RefreshToken is a refresh token handed to a user, stored as a SHA-256 hash.
+// Each refresh replaces the token with a new one of the same family, so a
+// token presented twice means it leaked, and its whole family is revoked.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID  string     `gorm:"not null;index" json:"-"` // shared by the tokens of one login
+	TokenHash string     `gorm:"not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"` // set when rotated
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// HostedAuthSession is a hosted auth link handed to a user. The notify
+// callback consumes it, so each link connects at most one account.
+type HostedAuthSession struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Nonce     string     `gorm:"not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	AccountID string     `json:"account_id,omitempty"` // the Unipile account it connected
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// WebhookEvent is a webhook received from Unipile. EventID is unique so
+// redelivered webhooks are stored (and processed) only once.
+type WebhookEvent struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	EventID         string     `gorm:"not null;uniqueIndex" json:"event_id"`
+	Type            string     `gorm:"not null;index" json:"type"`
+	AccountID       string     `gorm:"index" json:"account_id"`
+	LinkedAccountID *uint      `gorm:"index" json:"linked_account_id,omitempty"`
+	Payload         string     `gorm:"not null" json:"payload"`
+	ProcessedAt     *time.Time `gorm:"index" json:"processed_at,omitempty"`
+	Error           string     `json:"error,omitempty"`
+	Attempts        int        `gorm:"not null;default:0" json:"attempts"` // failed processing attempts
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// SyncedChat is a chat stored locally so the inbox can be read and searched
+// without a round trip to Unipile
+type SyncedChat struct {
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	LinkedAccountID    uint       `gorm:"not null;uniqueIndex:idx_synced_chats_account_chat" json:"linked_account_id"`
+	ChatID             string     `gorm:"not null;uniqueIndex:idx_synced_chats_account_chat" json:"chat_id"`
+	ProviderID         string     `json:"provider_id"`
+	AttendeeProviderID string     `json:"attendee_provider_id,omitempty"`
+	Name               string     `json:"name,omitempty"`
+	Type               string     `json:"type"`
+	UnreadCount        int        `json:"unread_count"`
+	LastActivityAt     *time.Time `json:"last_activity_at,omitempty"`
+	LastSyncedAt       *time.Time `json:"last_synced_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+
+	// Unipile cursor of the older messages still to be copied, empty once the
+	// whole history is stored
+	BackfillCursor string `json:"-"`
+}
+
+// MessageSyncState is where the message sync of a linked account left off
+type MessageSyncState struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	LinkedAccountID uint      `gorm:"not null;uniqueIndex" json:"linked_account_id"`
+	ChatCursor      string    `json:"-"` // Unipile cursor of the chats still to be copied, if any
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// SyncedMessage is a message stored locally. Its text is indexed in the
+// synced_messages_fts full-text table (see database.InitDatabase).
+type SyncedMessage struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	LinkedAccountID uint       `gorm:"not null;uniqueIndex:idx_synced_messages_account_message" json:"linked_account_id"`
+	MessageID       string     `gorm:"not null;uniqueIndex:idx_synced_messages_account_message" json:"message_id"`
+	ChatID          string     `gorm:"not null;index" json:"chat_id"`
+	ProviderID      string     `json:"provider_id,omitempty"`
+	SenderID        string     `json:"sender_id"`
+	SenderName      string     `json:"sender_name,omitempty"`
+	IsMe            bool       `json:"is_me"`
+	Text            string     `json:"text"`
+	SentAt          *time.Time `gorm:"index" json:"sent_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// Invitation statuses
+const (
+	InvitationStatusPending   = "PENDING"
+	InvitationStatusAccepted  = "ACCEPTED"
+	InvitationStatusWithdrawn = "WITHDRAWN"
+)
+
+// InvitationNoteMaxLength is LinkedIn's limit on connection request notes
+const InvitationNoteMaxLength = 300
+
+// Invitation is a LinkedIn connection request sent from a linked account
+type Invitation struct {
+	ID                  uint       `gorm:"primaryKey" json:"id"`
+	LinkedAccountID     uint       `gorm:"not null;index" json:"account_id"`
+	InvitationID        string     `gorm:"index" json:"invitation_id"` // Unipile invitation ID
+	RecipientProviderID string     `gorm:"not null;index" json:"recipient_provider_id"`
+	RecipientIdentifier string     `json:"recipient_identifier,omitempty"` // public identifier from the profile URL
+	RecipientName       string     `json:"recipient_name,omitempty"`
+	Note                string     `json:"note,omitempty"`
+	Status              string     `gorm:"not null;default:'PENDING';index" json:"status"`
+	SentAt              time.Time  `json:"sent_at"`
+	AcceptedAt          *time.Time `json:"accepted_at,omitempty"`
+	WithdrawnAt         *time.Time `json:"withdrawn_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// Outbound actions limited by account quotas
+const (
+	ActionInvite      = "invite"
+	ActionMessage     = "message"
+	ActionProfileView = "profile_view"
+)
+
+// QuotaUsage records one outbound action of a linked account, counted against
+// its quotas
+type QuotaUsage struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	LinkedAccountID uint      `gorm:"not null;index:idx_quota_usages_account_action" json:"account_id"`
+	Action          string    `gorm:"not null;index:idx_quota_usages_account_action" json:"action"`
+	CreatedAt       time.Time `gorm:"index" json:"created_at"`
+}
+
+// Queued action types
+const (
+	QueuedActionSendMessage    = "send_message"
+	QueuedActionStartChat      = "start_chat"
+	QueuedActionSendInvitation = "send_invitation"
+)
+
+// Queued action statuses
+const (
+	QueueStatusPending   = "PENDING"
+	QueueStatusRunning   = "RUNNING"
+	QueueStatusDone      = "DONE"
+	QueueStatusFailed    = "FAILED"
+	QueueStatusCancelled = "CANCELLED"
+)
+
+// QueuedAction is an outbound message or invitation waiting to be sent by the
+// action queue. Target is the chat ID for messages and the recipient's
+// provider ID for new chats and invitations.
+type QueuedAction struct {
+	ID              uint          `gorm:"primaryKey" json:"id"`
+	UserID          uint          `gorm:"not null;index" json:"user_id"`
+	LinkedAccountID uint          `gorm:"not null;index" json:"account_id"`
+	Type            string        `gorm:"not null" json:"type"`
+	Target          string        `gorm:"not null;index" json:"target"`
+	Payload         ActionPayload `gorm:"serializer:json" json:"payload"`
+	Status          string        `gorm:"not null;default:'PENDING';index:idx_queued_actions_status_scheduled" json:"status"`
+	ScheduledAt     time.Time     `gorm:"not null;index:idx_queued_actions_status_scheduled" json:"scheduled_at"` // not sent before this time
+	Attempts        int           `json:"attempts"`
+	LastError       string        `json:"last_error,omitempty"`
+	ResultID        string        `json:"result_id,omitempty"` // message, chat or invitation ID returned by Unipile
+	StartedAt       *time.Time    `json:"started_at,omitempty"`
+	CompletedAt     *time.Time    `json:"completed_at,omitempty"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+}
+
+// ActionPayload holds the content of a queued action
+type ActionPayload struct {
+	Text                string `json:"text,omitempty"`
+	Note                string `json:"note,omitempty"`
+	RecipientIdentifier string `json:"recipient_identifier,omitempty"`
+	RecipientName       string `json:"recipient_name,omitempty"`
+}
+
+// Campaign statuses
+const (
+	CampaignStatusDraft     = "DRAFT"
+	CampaignStatusActive    = "ACTIVE"
+	CampaignStatusPaused    = "PAUSED"
+	CampaignStatusCompleted = "COMPLETED"
+)
+
+// Campaign step types
+const (
+	CampaignStepInvite  = "invite"
+	CampaignStepWait    = "wait"
+	CampaignStepMessage = "message"
+)
+
+// Campaign is a multi-step outreach sequence run from one linked account
+// for a list of leads
+type Campaign struct {
+	ID              uint           `gorm:"primaryKey" json:"id"`
+	UserID          uint           `gorm:"not null;index" json:"user_id"`
+	LinkedAccountID uint           `gorm:"not null;index" json:"account_id"`
+	Name            string         `gorm:"not null" json:"name"`
+	Status          string         `gorm:"not null;default:'DRAFT';index" json:"status"`
+	Steps           []CampaignStep `gorm:"serializer:json" json:"steps"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// CampaignStep is one step of a campaign. Text is the invitation note or
+// the message; Days is the length of a wait. Message steps after an invite
+// step wait until the invitation is accepted, or until it times out.
+type CampaignStep struct {
+	Type string `json:"type" binding:"required,oneof=invite wait message"`
+	Days int    `json:"days,omitempty" binding:"min=0,max=90"`
+	Text string `json:"text,omitempty" binding:"max=8000"`
+}
+
+// Campaign lead statuses
+const (
+	CampaignLeadActive      = "ACTIVE"
+	CampaignLeadReplied     = "REPLIED"
+	CampaignLeadCompleted   = "COMPLETED"
+	CampaignLeadNotAccepted = "NOT_ACCEPTED"
+	CampaignLeadFailed      = "FAILED"
+)
+
+// CampaignLead tracks one lead's progress through a campaign. Identifier is
+// the public identifier from the profile URL (or a provider ID).
+type CampaignLead struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	CampaignID     uint       `gorm:"not null;uniqueIndex:idx_campaign_leads_campaign_identifier" json:"campaign_id"`
+	Identifier     string     `gorm:"not null;uniqueIndex:idx_campaign_leads_campaign_identifier" json:"identifier"`
+	ProfileURL     string     `json:"profile_url,omitempty"`
+	ProviderID     string     `json:"provider_id,omitempty"`
+	Name           string     `json:"name,omitempty"`
+	Status         string     `gorm:"not null;default:'ACTIVE';index" json:"status"`
+	CurrentStep    int        `json:"current_step"`
+	NextActionAt   time.Time  `json:"next_action_at"`
+	QueuedActionID *uint      `json:"queued_action_id,omitempty"` // outbound action in flight
+	ChatID         string     `json:"chat_id,omitempty"`
+	StartedAt      *time.Time `json:"started_at,omitempty"` // first outreach; replies count from here
+	InvitedAt      *time.Time `json:"invited_at,omitempty"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	MessagesSent   int        `json:"messages_sent"`
+	LastMessageAt  *time.Time `json:"last_message_at,omitempty"`
+	RepliedAt      *time.Time `json:"replied_at,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// MessageTemplate is a user's reusable message with text/template
+// placeholders such as {{.FirstName}}, filled from the recipient's profile
+type MessageTemplate struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Name      string    `gorm:"not null" json:"name"`
+	Body      string    `gorm:"not null" json:"body"`
+	Variables []string  `gorm:"serializer:json" json:"variables"` // placeholders used in Body
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Lead is a prospect imported from a spreadsheet. Leads are unique per user
+// by LinkedIn profile.
+type Lead struct {
+	ID               uint              `gorm:"primaryKey" json:"id"`
+	UserID           uint              `gorm:"not null;uniqueIndex:idx_leads_user_identifier" json:"user_id"`
+	PublicIdentifier string            `gorm:"not null;uniqueIndex:idx_leads_user_identifier" json:"public_identifier"`
+	ProfileURL       string            `gorm:"not null" json:"profile_url"`
+	FirstName        string            `json:"first_name"`
+	LastName         string            `json:"last_name"`
+	Company          string            `json:"company"`
+	CustomFields     map[string]string `gorm:"serializer:json" json:"custom_fields"`
+	CreatedAt        time.Time         `json:"created_at"`
+	UpdatedAt        time.Time         `json:"updated_at"`
+}
+
+// LeadList is a named group of a user's leads. A lead can be in several lists.
+type LeadList struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_lead_lists_user_name" json:"user_id"`
+	Name      string    `gorm:"not null;uniqueIndex:idx_lead_lists_user_name" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LeadListEntry puts a lead in a list
+type LeadListEntry struct {
+	LeadListID uint      `gorm:"primaryKey;autoIncrement:false"`
+	LeadID     uint      `gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt  time.Time // when the lead was added to the list
+}
+
+// Profile is a LinkedIn member profile, normalized from Unipile's response
+type Profile struct {
+	ProviderID       string              `json:"provider_id"`
+	PublicIdentifier string              `json:"public_identifier"`
+	ProfileURL       string              `json:"profile_url"`
+	FirstName        string              `json:"first_name"`
+	LastName         string              `json:"last_name"`
+	FullName         string              `json:"full_name"`
+	Headline         string              `json:"headline"`
+	Company          string              `json:"company"`  // of the current position
+	Position         string              `json:"position"` // current job title
+	Location         string              `json:"location"`
+	Experience       []ProfileExperience `json:"experience"` // most recent first
+}
+
+type ProfileExperience struct {
+	Company   string `json:"company"`
+	Position  string `json:"position"`
+	Location  string `json:"location,omitempty"`
+	StartDate string `json:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty"`
+	Current   bool   `json:"current"`
+}
+
+// CachedProfile is a profile fetched through a linked account, reused for
+// lookups by that account until it is older than the cache TTL
+type CachedProfile struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	LinkedAccountID  uint      `gorm:"not null;uniqueIndex:idx_cached_profiles_account_provider" json:"account_id"`
+	ProviderID       string    `gorm:"not null;uniqueIndex:idx_cached_profiles_account_provider" json:"provider_id"`
+	PublicIdentifier string    `gorm:"index" json:"public_identifier"` // lowercased
+	Profile          Profile   `gorm:"serializer:json" json:"profile"`
+	FetchedAt        time.Time `gorm:"not null" json:"fetched_at"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// TemplateData holds the variables available to message templates
+type TemplateData struct {
+	FirstName        string `json:"first_name"`
+	LastName         string `json:"last_name"`
+	FullName         string `json:"full_name"`
+	Headline         string `json:"headline"`
+	Company          string `json:"company"`
+	Position         string `json:"position"`
+	Location         string `json:"location"`
+	PublicIdentifier string `json:"public_identifier"`
+
+	// Custom holds a lead's imported custom fields, used as {{.Custom.field}}
+	Custom map[string]string `json:"custom,omitempty"`
+}
+
 // Request/Response DTOs
 
 type RegisterRequest struct {
@@ -47,13 +447,21 @@ type LoginRequest struct {
 }
 
 type AuthResponse struct {
-	Token string `json:"token"`
-	User  struct {
+	Token        string    `json:"token"` // short-lived access token
+	ExpiresAt    time.Time `json:"expires_at"`
+	RefreshToken string    `json:"refresh_token"`
+	User         struct {
 		ID    uint   `json:"id"`
 		Email string `json:"email"`
 	} `json:"user"`
 }
 
+// RefreshTokenRequest carries the refresh token of /auth/refresh and
+// /auth/logout
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 type LinkedInCookieRequest struct {
 	Cookie string `json:"cookie" binding:"required"`
 }
@@ -69,6 +477,358 @@ type LinkedInConnectResponse struct {
 	Account   LinkedAccount `json:"account"`
 }
 
+// LinkedInReconnectRequest accepts either a fresh li_at cookie or credentials
+type LinkedInReconnectRequest struct {
+	Cookie   string `json:"cookie" binding:"required_without=Username"`
+	Username string `json:"username" binding:"required_without=Cookie,required_with=Password"`
+	Password string `json:"password" binding:"required_with=Username"`
+}
+
+// ConnectAccountRequest connects an account of any provider. Which fields
+// are required depends on the auth method.
+type ConnectAccountRequest struct {
+	Method   string `json:"method"` // defaults to the provider's first auth method
+	Cookie   string `json:"cookie"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Email    string `json:"email"`
+}
+
+// HostedAuthLinkResponse is a Unipile hosted auth wizard link
+type HostedAuthLinkResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// HostedAuthNotification is the body of Unipile's hosted auth notify callback
+type HostedAuthNotification struct {
+	Status    string `json:"status"` // CREATION_SUCCESS once the account is connected
+	AccountID string `json:"account_id"`
+	Name      string `json:"name"` // the signed state of the link
+}
+
+type LinkedInCheckpointRequest struct {
+	Code string `json:"code"` // Not needed for IN_APP_VALIDATION, which is polled
+}
+
+type LinkedInCheckpointResponse struct {
+	Message        string    `json:"message"`
+	CheckpointType string    `json:"checkpoint_type"`
+	QRCode         string    `json:"qr_code,omitempty"` // for QRCODE checkpoints, to render and scan
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+// Chat types
+const (
+	ChatTypeDirect  = "direct"
+	ChatTypeGroup   = "group"
+	ChatTypeChannel = "channel"
+)
+
+// Chat is a LinkedIn conversation, normalized from Unipile
+type Chat struct {
+	ID                 string     `json:"id"`
+	AccountID          uint       `json:"account_id"` // LinkedAccount ID
+	ProviderID         string     `json:"provider_id"`
+	AttendeeProviderID string     `json:"attendee_provider_id,omitempty"`
+	Name               string     `json:"name,omitempty"`
+	Subject            string     `json:"subject,omitempty"`
+	Type               string     `json:"type"`
+	UnreadCount        int        `json:"unread_count"`
+	Archived           bool       `json:"archived"`
+	Muted              bool       `json:"muted"`
+	ReadOnly           bool       `json:"read_only"`
+	LastActivityAt     *time.Time `json:"last_activity_at,omitempty"`
+}
+
+type ChatListResponse struct {
+	Chats      []Chat `json:"chats"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Count      int    `json:"count"`
+}
+
+// Message is a LinkedIn chat message, normalized from Unipile
+type Message struct {
+	ID          string        `json:"id"`
+	ChatID      string        `json:"chat_id"`
+	AccountID   uint          `json:"account_id"` // LinkedAccount ID
+	ProviderID  string        `json:"provider_id"`
+	Sender      MessageSender `json:"sender"`
+	Text        string        `json:"text"`
+	SentAt      *time.Time    `json:"sent_at,omitempty"`
+	Seen        bool          `json:"seen"`
+	Edited      bool          `json:"edited"`
+	Deleted     bool          `json:"deleted"`
+	Attachments []Attachment  `json:"attachments"`
+}
+
+// MessageSender identifies who sent a message; IsMe is true for messages
+// sent from the linked account itself
+type MessageSender struct {
+	ID   string `json:"id"`
+	IsMe bool   `json:"is_me"`
+}
+
+// Attachment is the metadata of a file attached to a message
+type Attachment struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	FileName    string `json:"file_name,omitempty"`
+	FileSize    int64  `json:"file_size,omitempty"`
+	MimeType    string `json:"mime_type,omitempty"`
+	Unavailable bool   `json:"unavailable"`
+}
+
+type MessageListResponse struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	Count      int       `json:"count"`
+}
+
+// Outbound requests are queued; SendAt optionally delays them further.
+// Messages are given as Text or rendered from the template TemplateID.
+
+type SendMessageRequest struct {
+	Text       string     `json:"text" binding:"required_without=TemplateID,max=8000"`
+	TemplateID uint       `json:"template_id"`
+	SendAt     *time.Time `json:"send_at"`
+}
+
+type StartChatRequest struct {
+	AttendeeProviderID string     `json:"attendee_provider_id" binding:"required"`
+	Text               string     `json:"text" binding:"required_without=TemplateID,max=8000"`
+	TemplateID         uint       `json:"template_id"`
+	SendAt             *time.Time `json:"send_at"`
+}
+
+type QueuedActionResponse struct {
+	Message string       `json:"message"`
+	Action  QueuedAction `json:"action"`
+}
+
+type QueueListResponse struct {
+	Actions []QueuedAction `json:"actions"`
+	Count   int            `json:"count"`
+}
+
+// UpdateScheduleRequest sets the working hours queued actions of an account
+// are sent in
+type UpdateScheduleRequest struct {
+	Timezone          string   `json:"timezone" binding:"required"`
+	WorkingHoursStart string   `json:"working_hours_start" binding:"required"`
+	WorkingHoursEnd   string   `json:"working_hours_end" binding:"required"`
+	WorkingDays       []string `json:"working_days" binding:"required,min=1"`
+}
+
+// MessageSearchResult is a stored message matching a search, with the
+// matching terms wrapped in <mark> tags in Snippet (HTML-escaped otherwise)
+type MessageSearchResult struct {
+	MessageID string     `json:"message_id"`
+	ChatID    string     `json:"chat_id"`
+	AccountID uint       `json:"account_id"` // LinkedAccount ID
+	SenderID  string     `json:"sender_id"`
+	IsMe      bool       `json:"is_me"`
+	Text      string     `json:"text"`
+	Snippet   string     `json:"snippet"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+}
+
+type MessageSearchResponse struct {
+	Query   string                `json:"query"`
+	Results []MessageSearchResult `json:"results"`
+	Count   int                   `json:"count"`
+}
+
+// SendInvitationRequest names the recipient by provider ID or profile URL.
+// Note is limited to InvitationNoteMaxLength characters.
+type SendInvitationRequest struct {
+	ProviderID string     `json:"provider_id" binding:"required_without=ProfileURL"`
+	ProfileURL string     `json:"profile_url" binding:"required_without=ProviderID"`
+	Note       string     `json:"note" binding:"max=300"`
+	TemplateID uint       `json:"template_id"` // renders the note instead
+	SendAt     *time.Time `json:"send_at"`
+}
+
+type InvitationListResponse struct {
+	Invitations []Invitation `json:"invitations"`
+	Count       int          `json:"count"`
+}
+
+// QuotaWindow is the usage of one action within one rolling window.
+// Limit 0 means unlimited, in which case Remaining is omitted.
+type QuotaWindow struct {
+	Limit     int        `json:"limit"`
+	Used      int        `json:"used"`
+	Remaining *int       `json:"remaining,omitempty"`
+	ResetAt   *time.Time `json:"reset_at,omitempty"` // when the oldest counted action leaves the window
+}
+
+type ActionQuota struct {
+	Action string      `json:"action"`
+	Daily  QuotaWindow `json:"daily"`
+	Weekly QuotaWindow `json:"weekly"`
+}
+
+type QuotaResponse struct {
+	AccountID uint          `json:"account_id"`
+	Quotas    []ActionQuota `json:"quotas"`
+}
+
+// QuotaExceededResponse is returned with 429 when an action would exceed a quota
+type QuotaExceededResponse struct {
+	Error   string    `json:"error"`
+	Action  string    `json:"action"`
+	Window  string    `json:"window"`
+	Limit   int       `json:"limit"`
+	Used    int       `json:"used"`
+	ResetAt time.Time `json:"reset_at"`
+}
+
+// ProfileURLs lists the campaign's leads as LinkedIn profile URLs
+type CreateCampaignRequest struct {
+	Name        string         `json:"name" binding:"required,max=200"`
+	AccountID   uint           `json:"account_id" binding:"required"`
+	Steps       []CampaignStep `json:"steps" binding:"required,min=1,max=20,dive"`
+	ProfileURLs []string       `json:"profile_urls" binding:"max=5000"`
+}
+
+// UpdateCampaignRequest renames a campaign, replaces its steps (not while it
+// is active) and adds leads
+type UpdateCampaignRequest struct {
+	Name        string         `json:"name" binding:"max=200"`
+	Steps       []CampaignStep `json:"steps" binding:"omitempty,max=20,dive"`
+	ProfileURLs []string       `json:"profile_urls" binding:"max=5000"`
+}
+
+// CampaignStats counts the campaign's leads by progress
+type CampaignStats struct {
+	Leads           int `json:"leads"`
+	Active          int `json:"active"`
+	InvitationsSent int `json:"invitations_sent"`
+	Accepted        int `json:"accepted"`
+	MessagesSent    int `json:"messages_sent"`
+	Replied         int `json:"replied"`
+	Completed       int `json:"completed"`
+	NotAccepted     int `json:"not_accepted"`
+	Failed          int `json:"failed"`
+}
+
+type CampaignResponse struct {
+	Campaign
+	Stats CampaignStats `json:"stats"`
+}
+
+type CampaignListResponse struct {
+	Campaigns []CampaignResponse `json:"campaigns"`
+	Count     int                `json:"count"`
+}
+
+type CampaignLeadListResponse struct {
+	Leads []CampaignLead `json:"leads"`
+	Count int            `json:"count"`
+}
+
+type TemplateRequest struct {
+	Name string `json:"name" binding:"required,max=200"`
+	Body string `json:"body" binding:"required,max=8000"`
+}
+
+type TemplateListResponse struct {
+	Templates []MessageTemplate `json:"templates"`
+	Count     int               `json:"count"`
+}
+
+// TemplatePreviewRequest optionally overrides the sample profile variables
+type TemplatePreviewRequest struct {
+	Profile *TemplateData `json:"profile"`
+}
+
+type TemplatePreviewResponse struct {
+	Rendered         string       `json:"rendered"`
+	Variables        []string     `json:"variables"`
+	MissingVariables []string     `json:"missing_variables,omitempty"`
+	Profile          TemplateData `json:"profile"`
+}
+
+// TemplateErrorResponse is returned when a template can't be rendered for a
+// recipient because their profile lacks some variables
+type TemplateErrorResponse struct {
+	Error            string   `json:"error"`
+	MissingVariables []string `json:"missing_variables"`
+}
+
+// LeadColumnMapping names the CSV column of each lead field. Unset fields
+// default to columns with the field's name, or a common alias of it.
+type LeadColumnMapping struct {
+	ProfileURL string `json:"profile_url"`
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	FullName   string `json:"full_name"` // split when first and last name have no column
+	Company    string `json:"company"`
+
+	// CustomFields maps custom field names to columns. When unset, every
+	// column not used by another field becomes a custom field.
+	CustomFields map[string]string `json:"custom_fields"`
+}
+
+type LeadImportError struct {
+	Row   int    `json:"row"` // line in the file, the header being row 1
+	Error string `json:"error"`
+}
+
+type LeadImportResponse struct {
+	List       *LeadList         `json:"list,omitempty"`
+	Rows       int               `json:"rows"`
+	Created    int               `json:"created"`
+	Updated    int               `json:"updated"`
+	Duplicates int               `json:"duplicates"` // repeated profiles within the file
+	Errors     []LeadImportError `json:"errors"`
+}
+
+type LeadListResponse struct {
+	Leads      []Lead `json:"leads"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Count      int    `json:"count"`
+}
+
+type LeadListSummary struct {
+	LeadList
+	LeadCount int64 `json:"lead_count"`
+}
+
+type LeadListsResponse struct {
+	Lists []LeadListSummary `json:"lists"`
+	Count int               `json:"count"`
+}
+
+// BulkMessageRequest messages every lead of a list from one linked account,
+// with Text or a template rendered from each lead's imported fields
+type BulkMessageRequest struct {
+	AccountID  uint       `json:"account_id" binding:"required"`
+	Text       string     `json:"text" binding:"required_without=TemplateID,max=8000"`
+	TemplateID uint       `json:"template_id"`
+	SendAt     *time.Time `json:"send_at"`
+}
+
+type BulkMessageSkip struct {
+	LeadID           uint     `json:"lead_id"`
+	PublicIdentifier string   `json:"public_identifier"`
+	Error            string   `json:"error"`
+	MissingVariables []string `json:"missing_variables,omitempty"`
+}
+
+type BulkMessageResponse struct {
+	Message string            `json:"message"`
+	Queued  int               `json:"queued"`
+	Skipped []BulkMessageSkip `json:"skipped"`
+}
+
+type ProfileResponse struct {
+	Profile
+	Cached    bool      `json:"cached"` // served from the cache, without a LinkedIn profile view
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CampaignRepository handles campaign and campaign lead data operations
+type CampaignRepository struct {
+	db *gorm.DB
+}
+
+// NewCampaignRepository creates a new campaign repository
+func NewCampaignRepository(db *gorm.DB) *CampaignRepository {
+	return &CampaignRepository{db: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx
+func (r *CampaignRepository) WithContext(ctx context.Context) *CampaignRepository {
+	return &CampaignRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create creates a new campaign
+func (r *CampaignRepository) Create(campaign *models.Campaign) error {
+	return r.db.Create(campaign).Error
+}
+
+// FindByUserID finds all campaigns of a user, newest first
+func (r *CampaignRepository) FindByUserID(userID uint) ([]models.Campaign, error) {
+	var campaigns []models.Campaign
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&campaigns).Error
+	return campaigns, err
+}
+
+// FindByUserIDAndID finds a campaign of a user (for authorization)
+func (r *CampaignRepository) FindByUserIDAndID(userID, id uint) (*models.Campaign, error) {
+	var campaign models.Campaign
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&campaign).Error
+	if err != nil {
+		return nil, err
+	}
+	return &campaign, nil
+}
+
+// FindByStatus finds all campaigns with the given status
+func (r *CampaignRepository) FindByStatus(status string) ([]models.Campaign, error) {
+	var campaigns []models.Campaign
+	err := r.db.Where("status = ?", status).Find(&campaigns).Error
+	return campaigns, err
+}
+
+// Update saves changes to an existing campaign
+func (r *CampaignRepository) Update(campaign *models.Campaign) error {
+	return r.db.Save(campaign).Error
+}
+
+// UpdateStatus moves a campaign from one status to another, reporting false
+// if it had left the from status in the meantime
+func (r *CampaignRepository) UpdateStatus(id uint, from, to string) (bool, error) {
+	result := r.db.Model(&models.Campaign{}).Where("id = ? AND status = ?", id, from).Update("status", to)
+	return result.RowsAffected > 0, result.Error
+}
+
+// Delete deletes a campaign and its leads
+func (r *CampaignRepository) Delete(campaign *models.Campaign) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("campaign_id = ?", campaign.ID).Delete(&models.CampaignLead{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(campaign).Error
+	})
+}
+
+// AddLeads adds leads to a campaign, skipping those already in it. It returns
+// how many were added.
+func (r *CampaignRepository) AddLeads(leads []models.CampaignLead) (int64, error) {
+	if len(leads) == 0 {
+		return 0, nil
+	}
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&leads)
+	return result.RowsAffected, result.Error
+}
+
+// FindLeads lists the leads of a campaign, optionally filtered by status
+func (r *CampaignRepository) FindLeads(campaignID uint, status string) ([]models.CampaignLead, error) {
+	var leads []models.CampaignLead
+	db := r.db.Where("campaign_id = ?", campaignID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	err := db.Order("id ASC").Find(&leads).Error
+	return leads, err
+}
+
+// UpdateLead saves changes to an existing campaign lead
+func (r *CampaignRepository) UpdateLead(lead *models.CampaignLead) error {
+	return r.db.Save(lead).Error
+}
+
+// QueuedActionIDs returns the outbound actions in flight for a campaign's leads
+func (r *CampaignRepository) QueuedActionIDs(campaignID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&models.CampaignLead{}).
+		Where("campaign_id = ? AND queued_action_id IS NOT NULL", campaignID).
+		Pluck("queued_action_id", &ids).Error
+	return ids, err
+}
+
+// Stats counts a campaign's leads by progress
+func (r *CampaignRepository) Stats(campaignID uint) (models.CampaignStats, error) {
+	var stats models.CampaignStats
+	err := r.db.Model(&models.CampaignLead{}).
+		Select(`COUNT(*) AS leads,
+			COALESCE(SUM(status = ?), 0) AS active,
+			COUNT(invited_at) AS invitations_sent,
+			COUNT(accepted_at) AS accepted,
+			COALESCE(SUM(messages_sent), 0) AS messages_sent,
+			COALESCE(SUM(status = ?), 0) AS replied,
+			COALESCE(SUM(status = ?), 0) AS completed,
+			COALESCE(SUM(status = ?), 0) AS not_accepted,
+			COALESCE(SUM(status = ?), 0) AS failed`,
+			models.CampaignLeadActive, models.CampaignLeadReplied, models.CampaignLeadCompleted,
+			models.CampaignLeadNotAccepted, models.CampaignLeadFailed).
+		Where("campaign_id = ?", campaignID).
+		Scan(&stats).Error
+	return stats, err
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"gorm.io/gorm"
+)
+
+// exportBatchSize is how many rows exports read at a time
+const exportBatchSize = 500
+
+// ExportFilter selects the rows of an export: those of one user, optionally
+// dated within [From, To)
+type ExportFilter struct {
+	UserID uint
+	From   *time.Time
+	To     *time.Time
+}
+
+// ExportRepository reads a user's data in batches for exports
+type ExportRepository struct {
+	db *gorm.DB
+}
+
+// NewExportRepository creates a new export repository
+func NewExportRepository(db *gorm.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx
+func (r *ExportRepository) WithContext(ctx context.Context) *ExportRepository {
+	return &ExportRepository{db: r.db.WithContext(ctx)}
+}
+
+// Accounts streams the user's linked accounts, filtered on creation time
+func (r *ExportRepository) Accounts(filter ExportFilter, fn func([]models.LinkedAccount) error) error {
+	db := r.db.Where("user_id = ?", filter.UserID)
+	return findInBatches(dateRange(db, "linked_accounts.created_at", filter), fn)
+}
+
+// Leads streams the user's leads, filtered on import time
+func (r *ExportRepository) Leads(filter ExportFilter, fn func([]models.Lead) error) error {
+	db := r.db.Where("user_id = ?", filter.UserID)
+	return findInBatches(dateRange(db, "leads.created_at", filter), fn)
+}
+
+// Chats streams the synced chats of the user's accounts, filtered on last
+// activity
+func (r *ExportRepository) Chats(filter ExportFilter, fn func([]models.SyncedChat) error) error {
+	db := r.ownedBy(r.db.Model(&models.SyncedChat{}), "synced_chats", filter.UserID)
+	return findInBatches(dateRange(db, "synced_chats.last_activity_at", filter), fn)
+}
+
+// Messages streams the synced messages of the user's accounts, filtered on
+// send time
+func (r *ExportRepository) Messages(filter ExportFilter, fn func([]models.SyncedMessage) error) error {
+	db := r.ownedBy(r.db.Model(&models.SyncedMessage{}), "synced_messages", filter.UserID)
+	return findInBatches(dateRange(db, "synced_messages.sent_at", filter), fn)
+}
+
+// Invitations streams the invitations sent from the user's accounts,
+// filtered on send time
+func (r *ExportRepository) Invitations(filter ExportFilter, fn func([]models.Invitation) error) error {
+	db := r.ownedBy(r.db.Model(&models.Invitation{}), "invitations", filter.UserID)
+	return findInBatches(dateRange(db, "invitations.sent_at", filter), fn)
+}
+
+// QueuedActions streams the user's queued actions, filtered on creation time
+func (r *ExportRepository) QueuedActions(filter ExportFilter, fn func([]models.QueuedAction) error) error {
+	db := r.db.Where("user_id = ?", filter.UserID)
+	return findInBatches(dateRange(db, "queued_actions.created_at", filter), fn)
+}
+
+// Campaigns streams the user's campaigns, filtered on creation time
+func (r *ExportRepository) Campaigns(filter ExportFilter, fn func([]models.Campaign) error) error {
+	db := r.db.Where("user_id = ?", filter.UserID)
+	return findInBatches(dateRange(db, "campaigns.created_at", filter), fn)
+}
+
+// ownedBy narrows a query on a table keyed by linked_account_id to the
+// user's active linked accounts, like message search
+func (r *ExportRepository) ownedBy(db *gorm.DB, table string, userID uint) *gorm.DB {
+	return db.Select(table+".*").
+		Joins("JOIN linked_accounts ON linked_accounts.id = "+table+".linked_account_id").
+		Where("linked_accounts.user_id = ? AND linked_accounts.deleted_at IS NULL", userID)
+}
+
+// dateRange filters a query on a date column
+func dateRange(db *gorm.DB, column string, filter ExportFilter) *gorm.DB {
+	if filter.From != nil {
+		db = db.Where(column+" >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		db = db.Where(column+" < ?", *filter.To)
+	}
+	return db
+}
+
+// findInBatches reads the rows of a query in primary key order, a batch at a
+// time, so exports don't load whole tables
+func findInBatches[T any](db *gorm.DB, fn func([]T) error) error {
+	var batch []T
+	return db.FindInBatches(&batch, exportBatchSize, func(*gorm.DB, int) error {
+		return fn(batch)
+	}).Error
+}
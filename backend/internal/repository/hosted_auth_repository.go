@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"gorm.io/gorm"
+)
+
+// HostedAuthRepository handles hosted auth sessions
+type HostedAuthRepository struct {
+	db *gorm.DB
+}
+
+// NewHostedAuthRepository creates a new hosted auth repository
+func NewHostedAuthRepository(db *gorm.DB) *HostedAuthRepository {
+	return &HostedAuthRepository{db: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx
+func (r *HostedAuthRepository) WithContext(ctx context.Context) *HostedAuthRepository {
+	return &HostedAuthRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create creates a new hosted auth session
+func (r *HostedAuthRepository) Create(session *models.HostedAuthSession) error {
+	return r.db.Create(session).Error
+}
+
+// Consume marks a user's unexpired session as used by the given account. It
+// reports false if the session doesn't exist, has expired or was already
+// used; the check and update are one statement, so concurrent callbacks
+// can't both succeed.
+func (r *HostedAuthRepository) Consume(userID uint, nonce, accountID string, now time.Time) (bool, error) {
+	result := r.db.Model(&models.HostedAuthSession{}).
+		Where("user_id = ? AND nonce = ? AND used_at IS NULL AND expires_at > ?", userID, nonce, now).
+		Updates(map[string]interface{}{
+			"used_at":    now,
+			"account_id": accountID,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// DeleteExpired deletes sessions that expired before the given time
+func (r *HostedAuthRepository) DeleteExpired(before time.Time) error {
+	return r.db.Where("expires_at < ?", before).Delete(&models.HostedAuthSession{}).Error
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"gorm.io/gorm"
+)
+
+// InvitationRepository handles invitation data operations
+type InvitationRepository struct {
+	db *gorm.DB
+}
+
+// NewInvitationRepository creates a new invitation repository
+func NewInvitationRepository(db *gorm.DB) *InvitationRepository {
+	return &InvitationRepository{db: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx
+func (r *InvitationRepository) WithContext(ctx context.Context) *InvitationRepository {
+	return &InvitationRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create creates a new invitation
+func (r *InvitationRepository) Create(invitation *models.Invitation) error {
+	return r.db.Create(invitation).Error
+}
+
+// FindByLinkedAccountID finds the invitations sent from a linked account,
+// newest first, optionally filtered by status
+func (r *InvitationRepository) FindByLinkedAccountID(linkedAccountID uint, status string) ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	db := r.db.Where("linked_account_id = ?", linkedAccountID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	err := db.Order("sent_at DESC").Find(&invitations).Error
+	return invitations, err
+}
+
+// FindByLinkedAccountIDAndID finds an invitation of a linked account (for authorization)
+func (r *InvitationRepository) FindByLinkedAccountIDAndID(linkedAccountID, id uint) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.Where("id = ? AND linked_account_id = ?", id, linkedAccountID).First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// FindPendingByRecipient finds a pending invitation from a linked account to a recipient
+func (r *InvitationRepository) FindPendingByRecipient(linkedAccountID uint, providerID string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.Where("linked_account_id = ? AND recipient_provider_id = ? AND status = ?",
+		linkedAccountID, providerID, models.InvitationStatusPending).First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// FindLatestByRecipient finds the most recent invitation from a linked
+// account to a recipient
+func (r *InvitationRepository) FindLatestByRecipient(linkedAccountID uint, providerID string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.Where("linked_account_id = ? AND recipient_provider_id = ?", linkedAccountID, providerID).
+		Order("sent_at DESC").First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// MarkAccepted marks the pending invitations from a linked account to a
+// recipient as accepted
+func (r *InvitationRepository) MarkAccepted(linkedAccountID uint, providerID string, acceptedAt time.Time) (int64, error) {
+	result := r.db.Model(&models.Invitation{}).
+		Where("linked_account_id = ? AND recipient_provider_id = ? AND status = ?",
+			linkedAccountID, providerID, models.InvitationStatusPending).
+		Updates(map[string]interface{}{
+			"status":      models.InvitationStatusAccepted,
+			"accepted_at": acceptedAt,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// Update saves changes to an existing invitation
+func (r *InvitationRepository) Update(invitation *models.Invitation) error {
+	return r.db.Save(invitation).Error
+}
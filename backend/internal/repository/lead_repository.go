@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// identifierBatchSize keeps IN clauses below SQLite's variable limit
+const identifierBatchSize = 500
+
+// LeadRepository handles lead and lead list data operations
+type LeadRepository struct {
+	db *gorm.DB
+}
+
+// NewLeadRepository creates a new lead repository
+func NewLeadRepository(db *gorm.DB) *LeadRepository {
+	return &LeadRepository{db: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx
+func (r *LeadRepository) WithContext(ctx context.Context) *LeadRepository {
+	return &LeadRepository{db: r.db.WithContext(ctx)}
+}
+
+// FindByUserID lists a user's leads in import order, starting after the lead
+// afterID. listID narrows the listing to one list when non-zero.
+func (r *LeadRepository) FindByUserID(userID, listID, afterID uint, limit int) ([]models.Lead, error) {
+	var leads []models.Lead
+	db := r.db.Where("leads.user_id = ? AND leads.id > ?", userID, afterID)
+	if listID != 0 {
+		db = db.Joins("JOIN lead_list_entries ON lead_list_entries.lead_id = leads.id").
+			Where("lead_list_entries.lead_list_id = ?", listID)
+	}
+	err := db.Order("leads.id ASC").Limit(limit).Find(&leads).Error
+	return leads, err
+}
+
+// FindByUserIDAndID finds a lead of a user (for authorization)
+func (r *LeadRepository) FindByUserIDAndID(userID, id uint) (*models.Lead, error) {
+	var lead models.Lead
+	err := r.db.Where("user_id = ? AND id = ?", userID, id).First(&lead).Error
+	if err != nil {
+		return nil, err
+	}
+	return &lead, nil
+}
+
+// FindByIdentifiers finds a user's leads by public identifier
+func (r *LeadRepository) FindByIdentifiers(userID uint, identifiers []string) ([]models.Lead, error) {
+	var leads []models.Lead
+	for start := 0; start < len(identifiers); start += identifierBatchSize {
+		end := min(start+identifierBatchSize, len(identifiers))
+
+		var batch []models.Lead
+		err := r.db.Where("user_id = ? AND public_identifier IN ?", userID, identifiers[start:end]).Find(&batch).Error
+		if err != nil {
+			return nil, err
+		}
+		leads = append(leads, batch...)
+	}
+	return leads, nil
+}
+
+// SaveImport creates or updates imported leads and, when list is not nil,
+// adds them all to it, in one transaction
+func (r *LeadRepository) SaveImport(leads []models.Lead, list *models.LeadList) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range leads {
+			if err := tx.Save(&leads[i]).Error; err != nil {
+				return err
+			}
+		}
+		if list == nil || len(leads) == 0 {
+			return nil
+		}
+
+		entries := make([]models.LeadListEntry, len(leads))
+		for i := range leads {
+			entries[i] = models.LeadListEntry{LeadListID: list.ID, LeadID: leads[i].ID}
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(entries, identifierBatchSize).Error
+	})
+}
+
+// Delete deletes a lead and removes it from its lists
+func (r *LeadRepository) Delete(lead *models.Lead) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("lead_id = ?", lead.ID).Delete(&models.LeadListEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(lead).Error
+	})
+}
+
+// FindOrCreateList finds a user's list by name, creating it if needed
+func (r *LeadRepository) FindOrCreateList(userID uint, name string) (*models.LeadList, error) {
+	list := models.LeadList{UserID: userID, Name: name}
+	err := r.db.Where("user_id = ? AND name = ?", userID, name).FirstOrCreate(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// FindLists lists a user's lead lists by name, with their lead counts
+func (r *LeadRepository) FindLists(userID uint) ([]models.LeadListSummary, error) {
+	var lists []models.LeadListSummary
+	err := r.db.Model(&models.LeadList{}).
+		Select("lead_lists.*, COUNT(lead_list_entries.lead_id) AS lead_count").
+		Joins("LEFT JOIN lead_list_entries ON lead_list_entries.lead_list_id = lead_lists.id").
+		Where("lead_lists.user_id = ?", userID).
+		Group("lead_lists.id").
+		Order("lead_lists.name ASC").
+		Scan(&lists).Error
+	return lists, err
+}
+
+// FindListByUserIDAndID finds a lead list of a user (for authorization)
+func (r *LeadRepository) FindListByUserIDAndID(userID, id uint) (*models.LeadList, error) {
+	var list models.LeadList
+	err := r.db.Where("user_id = ? AND id = ?", userID, id).First(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// CountListLeads counts the leads of a list
+func (r *LeadRepository) CountListLeads(listID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.LeadListEntry{}).Where("lead_list_id = ?", listID).Count(&count).Error
+	return count, err
+}
+
+// FindListLeads finds every lead of a list
+func (r *LeadRepository) FindListLeads(listID uint) ([]models.Lead, error) {
+	var leads []models.Lead
+	err := r.db.Joins("JOIN lead_list_entries ON lead_list_entries.lead_id = leads.id").
+		Where("lead_list_entries.lead_list_id = ?", listID).
+		Order("leads.id ASC").
+		Find(&leads).Error
+	return leads, err
+}
+
+// DeleteList deletes a list. Its leads are kept.
+func (r *LeadRepository) DeleteList(list *models.LeadList) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("lead_list_id = ?", list.ID).Delete(&models.LeadListEntry{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(list).Error
+	})
+}
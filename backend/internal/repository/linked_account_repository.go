@@ -1,6 +1,9 @@
 package repository
 
 import (
+	"context"
+	"time"
+
 	"github.com/johnson7543/chatsheet-assessment/internal/models"
 	"gorm.io/gorm"
 )
@@ -15,6 +18,12 @@ func NewLinkedAccountRepository(db *gorm.DB) *LinkedAccountRepository {
 	return &LinkedAccountRepository{db: db}
 }
 
+// WithContext returns a copy of the repository whose queries run with ctx,
+// so they are logged with the request ID of a handler's context
+func (r *LinkedAccountRepository) WithContext(ctx context.Context) *LinkedAccountRepository {
+	return &LinkedAccountRepository{db: r.db.WithContext(ctx)}
+}
+
 // Create creates a new linked account
 func (r *LinkedAccountRepository) Create(account *models.LinkedAccount) error {
 	return r.db.Create(account).Error
@@ -27,6 +36,34 @@ func (r *LinkedAccountRepository) FindByUserID(userID uint) ([]models.LinkedAcco
 	return accounts, err
 }
 
+// FindAll finds every linked account across all users
+func (r *LinkedAccountRepository) FindAll() ([]models.LinkedAccount, error) {
+	var accounts []models.LinkedAccount
+	err := r.db.Order("id").Find(&accounts).Error
+	return accounts, err
+}
+
+// FindByAccountID finds the linked accounts bound to a Unipile account ID
+func (r *LinkedAccountRepository) FindByAccountID(accountID string) ([]models.LinkedAccount, error) {
+	var accounts []models.LinkedAccount
+	err := r.db.Where("account_id = ?", accountID).Find(&accounts).Error
+	return accounts, err
+}
+
+// FindByUserIDAndAccountID finds a user's linked account for a provider
+// account, including soft-deleted rows so they can be restored
+func (r *LinkedAccountRepository) FindByUserIDAndAccountID(userID uint, provider, accountID string) (*models.LinkedAccount, error) {
+	var account models.LinkedAccount
+	err := r.db.Unscoped().
+		Where("user_id = ? AND provider = ? AND account_id = ?", userID, provider, accountID).
+		Order("deleted_at IS NOT NULL, id DESC").
+		First(&account).Error
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
 // FindByID finds a linked account by ID
 func (r *LinkedAccountRepository) FindByID(id uint) (*models.LinkedAccount, error) {
 	var account models.LinkedAccount
@@ -47,6 +84,55 @@ func (r *LinkedAccountRepository) FindByUserIDAndID(userID, id uint) (*models.Li
 	return &account, nil
 }
 
+// Update saves changes to an existing linked account
+func (r *LinkedAccountRepository) Update(account *models.LinkedAccount) error {
+	return r.db.Save(account).Error
+}
+
+// UpdateStatus records the result of a health check without touching other fields
+func (r *LinkedAccountRepository) UpdateStatus(id uint, status, lastError string, checkedAt time.Time) error {
+	return r.db.Model(&models.LinkedAccount{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          status,
+		"last_error":      lastError,
+		"last_checked_at": checkedAt,
+	}).Error
+}
+
+// UpdateStatusUnlessPendingDeletion is UpdateStatus for health checks, which
+// must not overwrite the status of an account being deleted. It reports
+// false if the account is pending deletion.
+func (r *LinkedAccountRepository) UpdateStatusUnlessPendingDeletion(id uint, status, lastError string, checkedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.LinkedAccount{}).
+		Where("id = ? AND status <> ?", id, models.AccountStatusPendingDeletion).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"last_error":      lastError,
+			"last_checked_at": checkedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// UpdateOwnerProfile stores the owner's profile details of an account
+// without touching other fields
+func (r *LinkedAccountRepository) UpdateOwnerProfile(account *models.LinkedAccount) error {
+	return r.db.Model(&models.LinkedAccount{}).Where("id = ?", account.ID).Updates(map[string]interface{}{
+		"account_name":      account.AccountName,
+		"public_identifier": account.PublicIdentifier,
+		"profile_url":       account.ProfileURL,
+		"headline":          account.Headline,
+		"avatar_url":        account.AvatarURL,
+		"premium":           account.Premium,
+		"sales_navigator":   account.SalesNavigator,
+		"profile_synced_at": account.ProfileSyncedAt,
+	}).Error
+}
+
+// Restore saves an account and clears its soft delete
+func (r *LinkedAccountRepository) Restore(account *models.LinkedAccount) error {
+	account.DeletedAt = gorm.DeletedAt{}
+	return r.db.Unscoped().Save(account).Error
+}
+
 // Delete soft deletes a linked account
 func (r *LinkedAccountRepository) Delete(account *models.LinkedAccount) error {
 	return r.db.Delete(account).Error
@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Markers FTS5 puts around matched terms in snippets. They can't appear in
+// message text, so the snippet can be HTML-escaped before they become <mark>.
+const (
+	snippetOpen  = "\x02"
+	snippetClose = "\x03"
+)
+
+// snippetLength is the approximate length of fallback snippets, in characters
+const snippetLength = 160
+
+// MessageRepository handles synced chat and message data operations
+type MessageRepository struct {
+	db         *gorm.DB
+	ftsEnabled bool
+}
+
+// NewMessageRepository creates a new message repository. When ftsEnabled is
+// false, searches use LIKE instead of the FTS5 index.
+func NewMessageRepository(db *gorm.DB, ftsEnabled bool) *MessageRepository {
+	return &MessageRepository{db: db, ftsEnabled: ftsEnabled}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx
+func (r *MessageRepository) WithContext(ctx context.Context) *MessageRepository {
+	return &MessageRepository{db: r.db.WithContext(ctx), ftsEnabled: r.ftsEnabled}
+}
+
+// UpsertChat creates or updates a synced chat
+func (r *MessageRepository) UpsertChat(chat *models.SyncedChat) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "linked_account_id"}, {Name: "chat_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"provider_id", "attendee_provider_id", "name", "type", "unread_count",
+			"last_activity_at", "last_synced_at", "backfill_cursor", "updated_at",
+		}),
+	}).Create(chat).Error
+}
+
+// UpsertMessages creates or updates synced messages
+func (r *MessageRepository) UpsertMessages(messages []models.SyncedMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "linked_account_id"}, {Name: "message_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"text", "sender_id", "is_me", "sent_at", "updated_at"}),
+	}).Create(&messages).Error
+}
+
+// FindChat finds a synced chat of a linked account
+func (r *MessageRepository) FindChat(linkedAccountID uint, chatID string) (*models.SyncedChat, error) {
+	var chat models.SyncedChat
+	err := r.db.Where("linked_account_id = ? AND chat_id = ?", linkedAccountID, chatID).First(&chat).Error
+	if err != nil {
+		return nil, err
+	}
+	return &chat, nil
+}
+
+// FindChatsToBackfill finds the synced chats of a linked account whose older
+// messages are still to be copied, least recently synced first
+func (r *MessageRepository) FindChatsToBackfill(linkedAccountID uint, limit int) ([]models.SyncedChat, error) {
+	var chats []models.SyncedChat
+	err := r.db.Where("linked_account_id = ? AND backfill_cursor <> ''", linkedAccountID).
+		Order("last_synced_at ASC").
+		Limit(limit).
+		Find(&chats).Error
+	return chats, err
+}
+
+// UpdateBackfillCursor records where copying a chat's older messages continues
+func (r *MessageRepository) UpdateBackfillCursor(id uint, cursor string) error {
+	return r.db.Model(&models.SyncedChat{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"backfill_cursor": cursor,
+		"last_synced_at":  time.Now(),
+	}).Error
+}
+
+// FindChatCursor returns where the chat sync of a linked account left off,
+// or "" when no chats are left to copy
+func (r *MessageRepository) FindChatCursor(linkedAccountID uint) (string, error) {
+	var state models.MessageSyncState
+	err := r.db.Where("linked_account_id = ?", linkedAccountID).Limit(1).Find(&state).Error
+	return state.ChatCursor, err
+}
+
+// SaveChatCursor records where the chat sync of a linked account left off
+func (r *MessageRepository) SaveChatCursor(linkedAccountID uint, cursor string) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "linked_account_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"chat_cursor", "updated_at"}),
+	}).Create(&models.MessageSyncState{LinkedAccountID: linkedAccountID, ChatCursor: cursor}).Error
+}
+
+// HasReply reports whether a member has sent the linked account a message
+// since the given time, either from their provider ID or in the given chat
+func (r *MessageRepository) HasReply(linkedAccountID uint, senderID, chatID string, since time.Time) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.SyncedMessage{}).
+		Where("linked_account_id = ? AND is_me = ? AND sent_at > ?", linkedAccountID, false, since).
+		Where("sender_id = ? OR (chat_id = ? AND chat_id <> '')", senderID, chatID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// searchRow is a search hit as scanned from the database
+type searchRow struct {
+	models.SyncedMessage
+	Snippet string
+}
+
+// Search finds the user's stored messages matching query, best matches first.
+// accountID narrows the search to one linked account when non-zero. Only
+// messages of the user's active linked accounts are searched.
+func (r *MessageRepository) Search(userID, accountID uint, query string, limit int) ([]models.MessageSearchResult, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return []models.MessageSearchResult{}, nil
+	}
+
+	var db *gorm.DB
+	if r.ftsEnabled {
+		db = r.db.Table("synced_messages_fts").
+			Select("synced_messages.*, snippet(synced_messages_fts, 0, ?, ?, '…', 16) AS snippet", snippetOpen, snippetClose).
+			Joins("JOIN synced_messages ON synced_messages.id = synced_messages_fts.rowid").
+			Where("synced_messages_fts MATCH ?", ftsQuery(terms)).
+			Order("rank")
+	} else {
+		db = r.db.Table("synced_messages").Select("synced_messages.*, '' AS snippet")
+		for _, term := range terms {
+			db = db.Where("synced_messages.text LIKE ? ESCAPE '\\'", "%"+escapeLike(term)+"%")
+		}
+		db = db.Order("synced_messages.sent_at DESC")
+	}
+
+	db = db.Joins("JOIN linked_accounts ON linked_accounts.id = synced_messages.linked_account_id").
+		Where("linked_accounts.user_id = ? AND linked_accounts.deleted_at IS NULL", userID)
+	if accountID != 0 {
+		db = db.Where("synced_messages.linked_account_id = ?", accountID)
+	}
+
+	var rows []searchRow
+	if err := db.Limit(limit).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	results := make([]models.MessageSearchResult, 0, len(rows))
+	for _, row := range rows {
+		snippet := row.Snippet
+		if !r.ftsEnabled {
+			snippet = likeSnippet(row.Text, terms)
+		}
+		results = append(results, models.MessageSearchResult{
+			MessageID: row.MessageID,
+			ChatID:    row.ChatID,
+			AccountID: row.LinkedAccountID,
+			SenderID:  row.SenderID,
+			IsMe:      row.IsMe,
+			Text:      row.Text,
+			Snippet:   highlightSnippet(snippet),
+			SentAt:    row.SentAt,
+		})
+	}
+	return results, nil
+}
+
+// ftsQuery turns user input into an FTS5 query: every term is quoted so FTS
+// syntax characters are matched literally, terms are ANDed, and the last term
+// matches as a prefix so results appear while typing
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	quoted[len(quoted)-1] += "*"
+	return strings.Join(quoted, " ")
+}
+
+// escapeLike escapes LIKE wildcards in a search term
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+}
+
+// likeSnippet builds a snippet around the first matched term, with matches
+// wrapped in snippet markers, for searches that don't use FTS5
+func likeSnippet(text string, terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	pattern := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+
+	runes := []rune(text)
+	start := 0
+	if loc := pattern.FindStringIndex(text); loc != nil {
+		start = len([]rune(text[:loc[0]])) - snippetLength/4
+		if start < 0 {
+			start = 0
+		}
+	}
+	end := start + snippetLength
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	snippet := pattern.ReplaceAllString(string(runes[start:end]), snippetOpen+"$0"+snippetClose)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// highlightSnippet HTML-escapes a snippet and turns the markers into <mark> tags
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	return strings.NewReplacer(snippetOpen, "<mark>", snippetClose, "</mark>").Replace(escaped)
+}
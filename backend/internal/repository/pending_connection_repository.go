@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PendingConnectionRepository handles pending connection data operations
+type PendingConnectionRepository struct {
+	db *gorm.DB
+}
+
+// NewPendingConnectionRepository creates a new pending connection repository
+func NewPendingConnectionRepository(db *gorm.DB) *PendingConnectionRepository {
+	return &PendingConnectionRepository{db: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx
+func (r *PendingConnectionRepository) WithContext(ctx context.Context) *PendingConnectionRepository {
+	return &PendingConnectionRepository{db: r.db.WithContext(ctx)}
+}
+
+// Save creates or replaces the pending connection for the user
+func (r *PendingConnectionRepository) Save(pending *models.PendingConnection) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"linked_account_id", "provider", "account_id", "checkpoint_type", "expires_at", "updated_at"}),
+	}).Create(pending).Error
+}
+
+// FindByUserID finds the pending connection for a user
+func (r *PendingConnectionRepository) FindByUserID(userID uint) (*models.PendingConnection, error) {
+	var pending models.PendingConnection
+	err := r.db.Where("user_id = ?", userID).First(&pending).Error
+	if err != nil {
+		return nil, err
+	}
+	return &pending, nil
+}
+
+// Delete removes a pending connection
+func (r *PendingConnectionRepository) Delete(pending *models.PendingConnection) error {
+	return r.db.Delete(pending).Error
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ProfileRepository handles the LinkedIn profile cache
+type ProfileRepository struct {
+	db *gorm.DB
+}
+
+// NewProfileRepository creates a new profile repository
+func NewProfileRepository(db *gorm.DB) *ProfileRepository {
+	return &ProfileRepository{db: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx
+func (r *ProfileRepository) WithContext(ctx context.Context) *ProfileRepository {
+	return &ProfileRepository{db: r.db.WithContext(ctx)}
+}
+
+// Find finds a linked account's cached profile by provider ID or public
+// identifier
+func (r *ProfileRepository) Find(linkedAccountID uint, identifier string) (*models.CachedProfile, error) {
+	var profile models.CachedProfile
+	err := r.db.Where("linked_account_id = ?", linkedAccountID).
+		Where("provider_id = ? OR public_identifier = ?", identifier, strings.ToLower(identifier)).
+		Order("fetched_at DESC").
+		First(&profile).Error
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// Save stores a fetched profile, replacing the account's previous copy
+func (r *ProfileRepository) Save(profile *models.CachedProfile) error {
+	profile.PublicIdentifier = strings.ToLower(profile.PublicIdentifier)
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "linked_account_id"}, {Name: "provider_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"public_identifier", "profile", "fetched_at", "updated_at"}),
+	}).Create(profile).Error
+}
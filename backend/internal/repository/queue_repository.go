@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"gorm.io/gorm"
+)
+
+// QueueRepository handles queued action data operations
+type QueueRepository struct {
+	db *gorm.DB
+}
+
+// NewQueueRepository creates a new queue repository
+func NewQueueRepository(db *gorm.DB) *QueueRepository {
+	return &QueueRepository{db: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx
+func (r *QueueRepository) WithContext(ctx context.Context) *QueueRepository {
+	return &QueueRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create queues a new action
+func (r *QueueRepository) Create(action *models.QueuedAction) error {
+	return r.db.Create(action).Error
+}
+
+// CreateBatch queues several actions in one transaction, so either all of
+// them are queued or none
+func (r *QueueRepository) CreateBatch(actions []models.QueuedAction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&actions, 500).Error
+	})
+}
+
+// FindByUserID lists a user's queued actions, next to run first, optionally
+// filtered by linked account and status
+func (r *QueueRepository) FindByUserID(userID, linkedAccountID uint, status string, limit int) ([]models.QueuedAction, error) {
+	var actions []models.QueuedAction
+	db := r.db.Where("user_id = ?", userID)
+	if linkedAccountID != 0 {
+		db = db.Where("linked_account_id = ?", linkedAccountID)
+	}
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+	err := db.Order("scheduled_at ASC, id ASC").Limit(limit).Find(&actions).Error
+	return actions, err
+}
+
+// FindByUserIDAndID finds a queued action of a user (for authorization)
+func (r *QueueRepository) FindByUserIDAndID(userID, id uint) (*models.QueuedAction, error) {
+	var action models.QueuedAction
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&action).Error
+	if err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// FindByID finds a queued action by ID
+func (r *QueueRepository) FindByID(id uint) (*models.QueuedAction, error) {
+	var action models.QueuedAction
+	err := r.db.First(&action, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &action, nil
+}
+
+// FindDue finds pending actions scheduled at or before now, oldest first.
+// Actions of disconnected accounts, and of the excluded accounts, are left
+// out so they can't fill the batch and starve other accounts. Actions of
+// deleted accounts are kept, for the queue to fail them.
+func (r *QueueRepository) FindDue(now time.Time, excludedAccountIDs []uint, limit int) ([]models.QueuedAction, error) {
+	var actions []models.QueuedAction
+	db := r.db.Select("queued_actions.*").
+		Joins("JOIN linked_accounts ON linked_accounts.id = queued_actions.linked_account_id").
+		Where("queued_actions.status = ? AND queued_actions.scheduled_at <= ?", models.QueueStatusPending, now).
+		Where("linked_accounts.status = ? OR linked_accounts.deleted_at IS NOT NULL", models.AccountStatusOK)
+	if len(excludedAccountIDs) > 0 {
+		db = db.Where("queued_actions.linked_account_id NOT IN ?", excludedAccountIDs)
+	}
+	err := db.Order("queued_actions.scheduled_at ASC, queued_actions.id ASC").Limit(limit).Find(&actions).Error
+	return actions, err
+}
+
+// HasPending reports whether an action of the given type and target is
+// already waiting in a linked account's queue
+func (r *QueueRepository) HasPending(linkedAccountID uint, actionType, target string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.QueuedAction{}).
+		Where("linked_account_id = ? AND type = ? AND target = ? AND status IN ?", linkedAccountID, actionType, target,
+			[]string{models.QueueStatusPending, models.QueueStatusRunning}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// PendingRecipients returns the recipient identifiers of a linked account's
+// actions of the given type that are still pending or running
+func (r *QueueRepository) PendingRecipients(linkedAccountID uint, actionType string) (map[string]bool, error) {
+	var actions []models.QueuedAction
+	err := r.db.Select("id", "payload").
+		Where("linked_account_id = ? AND type = ? AND status IN ?", linkedAccountID, actionType,
+			[]string{models.QueueStatusPending, models.QueueStatusRunning}).
+		Find(&actions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := make(map[string]bool, len(actions))
+	for _, action := range actions {
+		if action.Payload.RecipientIdentifier != "" {
+			recipients[action.Payload.RecipientIdentifier] = true
+		}
+	}
+	return recipients, nil
+}
+
+// Claim marks a pending action as running. It returns false if the action is
+// no longer pending, e.g. because it was cancelled.
+func (r *QueueRepository) Claim(action *models.QueuedAction, startedAt time.Time) (bool, error) {
+	result := r.db.Model(&models.QueuedAction{}).
+		Where("id = ? AND status = ?", action.ID, models.QueueStatusPending).
+		Updates(map[string]interface{}{"status": models.QueueStatusRunning, "started_at": startedAt})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	action.Status, action.StartedAt = models.QueueStatusRunning, &startedAt
+	return true, nil
+}
+
+// Update saves changes to an existing queued action
+func (r *QueueRepository) Update(action *models.QueuedAction) error {
+	return r.db.Save(action).Error
+}
+
+// Cancel cancels a pending action. It returns false if the action is no
+// longer pending.
+func (r *QueueRepository) Cancel(action *models.QueuedAction, cancelledAt time.Time) (bool, error) {
+	result := r.db.Model(&models.QueuedAction{}).
+		Where("id = ? AND status = ?", action.ID, models.QueueStatusPending).
+		Updates(map[string]interface{}{"status": models.QueueStatusCancelled, "completed_at": cancelledAt})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	action.Status, action.CompletedAt = models.QueueStatusCancelled, &cancelledAt
+	return true, nil
+}
+
+// CancelPending cancels all pending actions of a user, optionally only those
+// of one linked account
+func (r *QueueRepository) CancelPending(userID, linkedAccountID uint, cancelledAt time.Time) (int64, error) {
+	db := r.db.Model(&models.QueuedAction{}).Where("user_id = ? AND status = ?", userID, models.QueueStatusPending)
+	if linkedAccountID != 0 {
+		db = db.Where("linked_account_id = ?", linkedAccountID)
+	}
+	result := db.Updates(map[string]interface{}{"status": models.QueueStatusCancelled, "completed_at": cancelledAt})
+	return result.RowsAffected, result.Error
+}
+
+// CancelByIDs cancels the given actions that are still pending
+func (r *QueueRepository) CancelByIDs(ids []uint, cancelledAt time.Time) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	result := r.db.Model(&models.QueuedAction{}).Where("id IN ? AND status = ?", ids, models.QueueStatusPending).
+		Updates(map[string]interface{}{"status": models.QueueStatusCancelled, "completed_at": cancelledAt})
+	return result.RowsAffected, result.Error
+}
+
+// FailRunning fails actions left running by a previous process
+func (r *QueueRepository) FailRunning(reason string, now time.Time) (int64, error) {
+	result := r.db.Model(&models.QueuedAction{}).Where("status = ?", models.QueueStatusRunning).
+		Updates(map[string]interface{}{"status": models.QueueStatusFailed, "last_error": reason, "completed_at": now})
+	return result.RowsAffected, result.Error
+}
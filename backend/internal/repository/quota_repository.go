@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"gorm.io/gorm"
+)
+
+// QuotaRepository handles quota usage data operations
+type QuotaRepository struct {
+	db *gorm.DB
+}
+
+// NewQuotaRepository creates a new quota repository
+func NewQuotaRepository(db *gorm.DB) *QuotaRepository {
+	return &QuotaRepository{db: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx
+func (r *QuotaRepository) WithContext(ctx context.Context) *QuotaRepository {
+	return &QuotaRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create records a quota usage
+func (r *QuotaRepository) Create(usage *models.QuotaUsage) error {
+	return r.db.Create(usage).Error
+}
+
+// Delete removes a quota usage, giving the action back
+func (r *QuotaRepository) Delete(usage *models.QuotaUsage) error {
+	return r.db.Delete(usage).Error
+}
+
+// CountSince counts the actions of a linked account since the given time
+func (r *QuotaRepository) CountSince(linkedAccountID uint, action string, since time.Time) (int, error) {
+	var count int64
+	err := r.db.Model(&models.QuotaUsage{}).
+		Where("linked_account_id = ? AND action = ? AND created_at > ?", linkedAccountID, action, since).
+		Count(&count).Error
+	return int(count), err
+}
+
+// NthOldestSince returns the time of the nth oldest action (starting at 1) of
+// a linked account since the given time
+func (r *QuotaRepository) NthOldestSince(linkedAccountID uint, action string, since time.Time, n int) (time.Time, error) {
+	var usage models.QuotaUsage
+	err := r.db.Where("linked_account_id = ? AND action = ? AND created_at > ?", linkedAccountID, action, since).
+		Order("created_at ASC").Offset(n - 1).First(&usage).Error
+	return usage.CreatedAt, err
+}
+
+// DeleteBefore removes usages too old to count against any quota
+func (r *QuotaRepository) DeleteBefore(before time.Time) error {
+	return r.db.Where("created_at <= ?", before).Delete(&models.QuotaUsage{}).Error
+}
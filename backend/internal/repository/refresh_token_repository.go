@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"gorm.io/gorm"
+)
+
+// RefreshTokenRepository handles refresh token data operations
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepository creates a new refresh token repository
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx
+func (r *RefreshTokenRepository) WithContext(ctx context.Context) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create creates a new refresh token
+func (r *RefreshTokenRepository) Create(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByHash finds a refresh token by the hash of its value
+func (r *RefreshTokenRepository) FindByHash(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Rotate marks an unused, unrevoked token as used and stores its successor,
+// in one transaction. It reports false, storing nothing, if the token was
+// already used or revoked; the check and update are one statement, so
+// concurrent refreshes with the same token can't both rotate it.
+func (r *RefreshTokenRepository) Rotate(id uint, successor *models.RefreshToken, now time.Time) (bool, error) {
+	rotated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+			Update("used_at", now)
+		if result.Error != nil || result.RowsAffected != 1 {
+			return result.Error
+		}
+		if err := tx.Create(successor).Error; err != nil {
+			return err
+		}
+		rotated = true
+		return nil
+	})
+	return rotated, err
+}
+
+// RevokeFamily revokes every token of a family that isn't revoked yet
+func (r *RefreshTokenRepository) RevokeFamily(familyID string, now time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+}
+
+// DeleteExpired deletes tokens that expired before the given time
+func (r *RefreshTokenRepository) DeleteExpired(before time.Time) error {
+	return r.db.Where("expires_at < ?", before).Delete(&models.RefreshToken{}).Error
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"gorm.io/gorm"
+)
+
+// TemplateRepository handles message template data operations
+type TemplateRepository struct {
+	db *gorm.DB
+}
+
+// NewTemplateRepository creates a new template repository
+func NewTemplateRepository(db *gorm.DB) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx
+func (r *TemplateRepository) WithContext(ctx context.Context) *TemplateRepository {
+	return &TemplateRepository{db: r.db.WithContext(ctx)}
+}
+
+// Create creates a new template
+func (r *TemplateRepository) Create(tmpl *models.MessageTemplate) error {
+	return r.db.Create(tmpl).Error
+}
+
+// FindByUserID finds all templates of a user, sorted by name
+func (r *TemplateRepository) FindByUserID(userID uint) ([]models.MessageTemplate, error) {
+	var templates []models.MessageTemplate
+	err := r.db.Where("user_id = ?", userID).Order("name ASC").Find(&templates).Error
+	return templates, err
+}
+
+// FindByUserIDAndID finds a template of a user (for authorization)
+func (r *TemplateRepository) FindByUserIDAndID(userID, id uint) (*models.MessageTemplate, error) {
+	var tmpl models.MessageTemplate
+	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&tmpl).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// Update saves changes to an existing template
+func (r *TemplateRepository) Update(tmpl *models.MessageTemplate) error {
+	return r.db.Save(tmpl).Error
+}
+
+// Delete deletes a template
+func (r *TemplateRepository) Delete(tmpl *models.MessageTemplate) error {
+	return r.db.Delete(tmpl).Error
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+
 	"github.com/johnson7543/chatsheet-assessment/internal/models"
 	"gorm.io/gorm"
 )
@@ -15,6 +17,11 @@ func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// WithContext returns a copy of the repository whose queries run with ctx
+func (r *UserRepository) WithContext(ctx context.Context) *UserRepository {
+	return &UserRepository{db: r.db.WithContext(ctx)}
+}
+
 // Create creates a new user
 func (r *UserRepository) Create(user *models.User) error {
 	return r.db.Create(user).Error
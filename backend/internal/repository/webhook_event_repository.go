@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookEventRepository handles webhook event data operations
+type WebhookEventRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookEventRepository creates a new webhook event repository
+func NewWebhookEventRepository(db *gorm.DB) *WebhookEventRepository {
+	return &WebhookEventRepository{db: db}
+}
+
+// WithContext returns a copy of the repository whose queries run with ctx
+func (r *WebhookEventRepository) WithContext(ctx context.Context) *WebhookEventRepository {
+	return &WebhookEventRepository{db: r.db.WithContext(ctx)}
+}
+
+// CreateIfNew stores the event unless one with the same EventID exists.
+// It reports whether the event was new.
+func (r *WebhookEventRepository) CreateIfNew(event *models.WebhookEvent) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "event_id"}},
+		DoNothing: true,
+	}).Create(event)
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindByID finds a webhook event by ID
+func (r *WebhookEventRepository) FindByID(id uint) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	err := r.db.First(&event, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+// FindUnprocessed finds events that have not been processed yet, oldest first
+func (r *WebhookEventRepository) FindUnprocessed(limit int) ([]models.WebhookEvent, error) {
+	var events []models.WebhookEvent
+	err := r.db.Where("processed_at IS NULL").Order("id").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// MarkProcessed records that an event was handled, with the error if it failed
+func (r *WebhookEventRepository) MarkProcessed(event *models.WebhookEvent, linkedAccountID *uint, processErr string) error {
+	now := time.Now()
+	event.ProcessedAt = &now
+	event.LinkedAccountID = linkedAccountID
+	event.Error = processErr
+	return r.db.Model(event).Updates(map[string]interface{}{
+		"processed_at":      now,
+		"linked_account_id": linkedAccountID,
+		"error":             processErr,
+	}).Error
+}
+
+// RecordFailure records a failed attempt at processing an event, leaving it
+// unprocessed so it's tried again
+func (r *WebhookEventRepository) RecordFailure(event *models.WebhookEvent, processErr string) error {
+	event.Attempts++
+	event.Error = processErr
+	return r.db.Model(event).Updates(map[string]interface{}{
+		"attempts": gorm.Expr("attempts + 1"),
+		"error":    processErr,
+	}).Error
+}
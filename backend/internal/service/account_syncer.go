@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+)
+
+// deletionRetryInterval is how often pending Unipile deletions are retried
+// while the status sync is disabled
+const deletionRetryInterval = 15 * time.Minute
+
+// AccountSyncer periodically refreshes the health status of every linked
+// account from Unipile's GET /accounts/{id}, along with its owner's profile
+// details from GET /users/me
+type AccountSyncer struct {
+	unipile  UnipileClient
+	accounts *repository.LinkedAccountRepository
+	interval time.Duration
+}
+
+// NewAccountSyncer creates a new account syncer
+func NewAccountSyncer(unipile UnipileClient, accounts *repository.LinkedAccountRepository, interval time.Duration) *AccountSyncer {
+	return &AccountSyncer{
+		unipile:  unipile,
+		accounts: accounts,
+		interval: interval,
+	}
+}
+
+// Start runs a sync immediately and then on every interval until ctx is done.
+// A non-positive interval disables the status sync, but pending deletions are
+// still retried every deletionRetryInterval.
+func (s *AccountSyncer) Start(ctx context.Context) {
+	run, interval := s.SyncAll, s.interval
+	if interval <= 0 {
+		slog.Info("Account syncer disabled, only retrying pending deletions")
+		run, interval = s.RetryDeletions, deletionRetryInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			run(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SyncAll refreshes the status of every linked account and retries pending
+// Unipile deletions
+func (s *AccountSyncer) SyncAll(ctx context.Context) {
+	accounts, err := s.accounts.FindAll()
+	if err != nil {
+		slog.ErrorContext(ctx, "Account sync failed to load accounts", "error", err)
+		return
+	}
+
+	for i := range accounts {
+		if ctx.Err() != nil {
+			return
+		}
+		if accounts[i].Status == models.AccountStatusPendingDeletion {
+			s.retryDeletion(ctx, &accounts[i])
+			continue
+		}
+		s.SyncAccount(ctx, &accounts[i])
+	}
+}
+
+// RetryDeletions retries the Unipile deletion of every account pending
+// deletion
+func (s *AccountSyncer) RetryDeletions(ctx context.Context) {
+	accounts, err := s.accounts.FindAll()
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to load accounts pending deletion", "error", err)
+		return
+	}
+
+	for i := range accounts {
+		if ctx.Err() != nil {
+			return
+		}
+		if accounts[i].Status == models.AccountStatusPendingDeletion {
+			s.retryDeletion(ctx, &accounts[i])
+		}
+	}
+}
+
+// retryDeletion deletes the account from Unipile again and, once that
+// succeeds, soft deletes the local row
+func (s *AccountSyncer) retryDeletion(ctx context.Context, account *models.LinkedAccount) {
+	if err := s.unipile.DeleteAccount(ctx, account.AccountID); err != nil {
+		slog.WarnContext(ctx, "Retrying deletion of account failed", "account_id", account.ID, "error", err)
+		if err := s.accounts.UpdateStatus(account.ID, models.AccountStatusPendingDeletion, err.Error(), time.Now()); err != nil {
+			slog.ErrorContext(ctx, "Failed to update status of account", "account_id", account.ID, "error", err)
+		}
+		return
+	}
+
+	if err := s.accounts.Delete(account); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete account", "account_id", account.ID, "error", err)
+		return
+	}
+	slog.InfoContext(ctx, "Account deleted from Unipile after retry", "account_id", account.ID)
+}
+
+// SyncAccount fetches one account from Unipile and stores its status,
+// check time and last error. The owner's profile is refreshed too while a
+// LinkedIn account is healthy.
+func (s *AccountSyncer) SyncAccount(ctx context.Context, account *models.LinkedAccount) {
+	status, lastError := account.Status, ""
+
+	remote, err := s.unipile.GetAccount(ctx, account.AccountID)
+	var apiErr *APIError
+	switch {
+	case err == nil:
+		status = accountStatus(remote.Status())
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		// Removed on the Unipile side
+		status, lastError = models.AccountStatusDisconnected, err.Error()
+	default:
+		// Unipile unreachable: keep the previous status but record why
+		lastError = err.Error()
+	}
+
+	checkedAt := time.Now()
+	updated, err := s.accounts.UpdateStatusUnlessPendingDeletion(account.ID, status, lastError, checkedAt)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to update status of account", "account_id", account.ID, "error", err)
+		return
+	}
+	if !updated {
+		// Removed by its owner while the sync was running
+		return
+	}
+
+	if status != account.Status {
+		slog.InfoContext(ctx, "Account status changed", "account_id", account.ID, "from", account.Status, "to", status)
+	}
+	account.Status, account.LastError, account.LastCheckedAt = status, lastError, &checkedAt
+
+	if status == models.AccountStatusOK && account.Provider == models.ProviderLinkedIn {
+		s.syncOwnerProfile(ctx, account)
+	}
+}
+
+// syncOwnerProfile refreshes the profile details of the account's owner. A
+// failure keeps the previous details and doesn't affect the account status.
+func (s *AccountSyncer) syncOwnerProfile(ctx context.Context, account *models.LinkedAccount) {
+	profile, err := s.unipile.GetOwnProfile(ctx, account.AccountID)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to fetch owner profile", "account_id", account.ID, "error", err)
+		return
+	}
+
+	ApplyOwnProfile(account, profile)
+	if err := s.accounts.UpdateOwnerProfile(account); err != nil {
+		slog.ErrorContext(ctx, "Failed to update owner profile", "account_id", account.ID, "error", err)
+	}
+}
+
+// ApplyOwnProfile copies the owner's profile details onto a linked account
+func ApplyOwnProfile(account *models.LinkedAccount, profile *OwnProfile) {
+	if name := strings.TrimSpace(profile.FirstName + " " + profile.LastName); name != "" {
+		account.AccountName = name
+	}
+	account.PublicIdentifier = profile.PublicIdentifier
+	account.ProfileURL = profile.PublicProfileURL
+	if account.ProfileURL == "" {
+		account.ProfileURL = profileURL(profile.PublicIdentifier)
+	}
+	account.Headline = profile.Occupation
+	account.AvatarURL = profile.ProfilePictureURL
+	account.Premium = profile.Premium
+	account.SalesNavigator = profile.SalesNavigator != nil
+
+	now := time.Now()
+	account.ProfileSyncedAt = &now
+}
+
+// accountStatus maps a Unipile source status onto a LinkedAccount status
+func accountStatus(status string) string {
+	switch status {
+	case AccountStatusOK, "CREATION_SUCCESS", "RECONNECTED", "SYNC_SUCCESS":
+		return models.AccountStatusOK
+	case AccountStatusCredentials:
+		return models.AccountStatusCredentials
+	case AccountStatusDisconnected:
+		return models.AccountStatusDisconnected
+	case AccountStatusConnecting:
+		return models.AccountStatusConnecting
+	default:
+		return models.AccountStatusError
+	}
+}
@@ -0,0 +1,431 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/config"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+)
+
+// interruptedActionError is the error of actions that were running when the
+// process stopped
+const interruptedActionError = "interrupted by a restart and may have been sent; check LinkedIn before queueing it again"
+
+// dispatchBatchSize bounds how many due actions are considered per poll
+const dispatchBatchSize = 100
+
+// ActionQueue sends queued messages and invitations through a worker pool.
+// Each linked account runs at most one action at a time, only within its
+// working hours and with a random pause after each action, so its activity
+// looks human to LinkedIn.
+type ActionQueue struct {
+	unipile     UnipileClient
+	actions     *repository.QueueRepository
+	accounts    *repository.LinkedAccountRepository
+	invitations *repository.InvitationRepository
+	quotas      *QuotaService
+	profiles    *ProfileService
+	cfg         config.QueueConfig
+
+	jobs chan *models.QueuedAction
+
+	mu     sync.Mutex
+	busy   map[uint]bool      // accounts with an action in flight
+	nextAt map[uint]time.Time // earliest start of each account's next action
+}
+
+// NewActionQueue creates a new action queue
+func NewActionQueue(unipile UnipileClient, actions *repository.QueueRepository, accounts *repository.LinkedAccountRepository,
+	invitations *repository.InvitationRepository, quotas *QuotaService, profiles *ProfileService, cfg config.QueueConfig) *ActionQueue {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 5 * time.Second
+	}
+	if cfg.MaxDelay < cfg.MinDelay {
+		cfg.MaxDelay = cfg.MinDelay
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+
+	return &ActionQueue{
+		unipile:     unipile,
+		actions:     actions,
+		accounts:    accounts,
+		invitations: invitations,
+		quotas:      quotas,
+		profiles:    profiles,
+		cfg:         cfg,
+		jobs:        make(chan *models.QueuedAction),
+		busy:        make(map[uint]bool),
+		nextAt:      make(map[uint]time.Time),
+	}
+}
+
+// Enqueue stores a new action. It runs at ScheduledAt, or as soon as
+// possible when that's unset.
+func (q *ActionQueue) Enqueue(ctx context.Context, action *models.QueuedAction) error {
+	action.Status = models.QueueStatusPending
+	if action.ScheduledAt.IsZero() {
+		action.ScheduledAt = time.Now()
+	}
+	return q.actions.WithContext(ctx).Create(action)
+}
+
+// EnqueueAll adds several actions to the queue in one transaction, so either
+// all of them are queued or none
+func (q *ActionQueue) EnqueueAll(ctx context.Context, actions []models.QueuedAction) error {
+	now := time.Now()
+	for i := range actions {
+		actions[i].Status = models.QueueStatusPending
+		if actions[i].ScheduledAt.IsZero() {
+			actions[i].ScheduledAt = now
+		}
+	}
+	return q.actions.WithContext(ctx).CreateBatch(actions)
+}
+
+// CheckQuota returns a *QuotaExceededError if a linked account has already
+// used up the quota an action type counts against
+func (q *ActionQueue) CheckQuota(ctx context.Context, linkedAccountID uint, actionType string) error {
+	return q.quotas.Check(ctx, linkedAccountID, actionQuota(actionType))
+}
+
+// PendingRecipients returns the recipients of a linked account's queued
+// actions of the given type
+func (q *ActionQueue) PendingRecipients(ctx context.Context, linkedAccountID uint, actionType string) (map[string]bool, error) {
+	return q.actions.WithContext(ctx).PendingRecipients(linkedAccountID, actionType)
+}
+
+// HasPending reports whether an action of the given type and target is
+// already queued for a linked account
+func (q *ActionQueue) HasPending(ctx context.Context, linkedAccountID uint, actionType, target string) (bool, error) {
+	return q.actions.WithContext(ctx).HasPending(linkedAccountID, actionType, target)
+}
+
+// Start fails actions interrupted by a previous shutdown, starts the workers
+// and dispatches due actions every poll interval until ctx is done
+func (q *ActionQueue) Start(ctx context.Context) {
+	// An interrupted action may already have been sent, and sending it again
+	// would message or invite the same person twice
+	if n, err := q.actions.FailRunning(interruptedActionError, time.Now()); err != nil {
+		slog.Error("Failed to fail interrupted actions", "error", err)
+	} else if n > 0 {
+		slog.Warn("Failed interrupted actions", "count", n)
+	}
+
+	for i := 0; i < q.cfg.Workers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case action := <-q.jobs:
+					q.run(ctx, action)
+				}
+			}
+		}()
+	}
+
+	go func() {
+		ticker := time.NewTicker(q.cfg.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			q.dispatch(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// dispatch hands due actions of idle accounts within their working hours to
+// the workers. Each account is looked at once per poll; batches are re-read
+// without the accounts seen so far, so accounts that can't send don't starve
+// the others.
+func (q *ActionQueue) dispatch(ctx context.Context) {
+	now := time.Now()
+	seen := q.unready(now)
+
+	for {
+		due, err := q.actions.FindDue(now, seen, dispatchBatchSize)
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to load due actions", "error", err)
+			return
+		}
+
+		visited := make(map[uint]bool)
+		for i := range due {
+			action := &due[i]
+			if visited[action.LinkedAccountID] {
+				continue
+			}
+			visited[action.LinkedAccountID] = true
+			seen = append(seen, action.LinkedAccountID)
+
+			if !q.dispatchAction(ctx, action, now) {
+				return
+			}
+		}
+
+		if len(due) < dispatchBatchSize {
+			return
+		}
+	}
+}
+
+// dispatchAction hands the oldest due action of an account to the workers if
+// the account can send now. It returns false once ctx is done.
+func (q *ActionQueue) dispatchAction(ctx context.Context, action *models.QueuedAction, now time.Time) bool {
+	account, err := q.accounts.FindByID(action.LinkedAccountID)
+	if err != nil {
+		q.fail(action, "linked account not found")
+		return true
+	}
+	// Disconnected accounts keep their actions until reconnected or cancelled
+	if account.Status != models.AccountStatusOK {
+		return true
+	}
+
+	hours, err := AccountWorkingHours(account)
+	if err != nil {
+		slog.ErrorContext(ctx, "Invalid working hours", "account_id", account.ID, "error", err)
+		return true
+	}
+	if !hours.Contains(now) {
+		return true
+	}
+
+	claimed, err := q.actions.Claim(action, now)
+	if err != nil {
+		slog.ErrorContext(ctx, "Failed to claim action", "action_id", action.ID, "error", err)
+		return true
+	}
+	if !claimed {
+		return true
+	}
+
+	q.setBusy(action.LinkedAccountID, true)
+	select {
+	case q.jobs <- action:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// unready lists the accounts with an action in flight or still pausing after
+// the previous one
+func (q *ActionQueue) unready(now time.Time) []uint {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var ids []uint
+	for id, busy := range q.busy {
+		if busy {
+			ids = append(ids, id)
+		}
+	}
+	for id, next := range q.nextAt {
+		if now.Before(next) && !q.busy[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (q *ActionQueue) setBusy(linkedAccountID uint, busy bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.busy[linkedAccountID] = busy
+}
+
+// pause delays the next action of an account by a random delay between
+// MinDelay and MaxDelay
+func (q *ActionQueue) pause(linkedAccountID uint) {
+	delay := q.cfg.MinDelay
+	if spread := q.cfg.MaxDelay - q.cfg.MinDelay; spread > 0 {
+		delay += time.Duration(rand.Int63n(int64(spread) + 1))
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nextAt[linkedAccountID] = time.Now().Add(delay)
+}
+
+// run executes one claimed action and records the outcome
+func (q *ActionQueue) run(ctx context.Context, action *models.QueuedAction) {
+	defer q.setBusy(action.LinkedAccountID, false)
+
+	account, err := q.accounts.FindByID(action.LinkedAccountID)
+	if err != nil {
+		q.fail(action, "linked account not found")
+		return
+	}
+
+	usage, err := q.quotas.Reserve(ctx, account.ID, actionQuota(action.Type))
+	if err != nil {
+		var exceeded *QuotaExceededError
+		if errors.As(err, &exceeded) {
+			// Wait for the quota instead of failing
+			q.reschedule(action, exceeded.ResetAt, err.Error())
+			return
+		}
+		q.reschedule(action, time.Now().Add(q.cfg.PollInterval), err.Error())
+		return
+	}
+
+	action.Attempts++
+	resultID, err := q.execute(ctx, account, action)
+	if err != nil {
+		if releaseErr := q.quotas.Release(ctx, usage); releaseErr != nil {
+			slog.ErrorContext(ctx, "Failed to release quota usage", "usage_id", usage.ID, "error", releaseErr)
+		}
+
+		var exceeded *QuotaExceededError
+		if errors.As(err, &exceeded) {
+			// Out of profile views to resolve the recipient with
+			action.Attempts--
+			q.reschedule(action, exceeded.ResetAt, err.Error())
+			return
+		}
+
+		// A request that may have gone through must not be sent again
+		if errors.Is(err, ErrMaybeDelivered) {
+			q.fail(action, err.Error()+"; check LinkedIn before queueing it again")
+			return
+		}
+
+		// A rejected request won't succeed later; Unipile being down might
+		var apiErr *APIError
+		retry := !errors.As(err, &apiErr) || apiErr.Retryable()
+		if retry && action.Attempts < q.cfg.MaxAttempts && ctx.Err() == nil {
+			backoff := time.Duration(action.Attempts) * q.cfg.MaxDelay
+			q.reschedule(action, time.Now().Add(backoff), err.Error())
+			return
+		}
+		q.fail(action, err.Error())
+		return
+	}
+
+	q.pause(account.ID)
+
+	now := time.Now()
+	action.Status = models.QueueStatusDone
+	action.ResultID = resultID
+	action.LastError = ""
+	action.CompletedAt = &now
+	if err := q.actions.Update(action); err != nil {
+		slog.ErrorContext(ctx, "Failed to complete action", "action_id", action.ID, "error", err)
+	}
+}
+
+// execute performs the Unipile call of an action and returns the ID of what
+// it created
+func (q *ActionQueue) execute(ctx context.Context, account *models.LinkedAccount, action *models.QueuedAction) (string, error) {
+	switch action.Type {
+	case models.QueuedActionSendMessage:
+		sent, err := q.unipile.SendMessage(ctx, action.Target, action.Payload.Text)
+		if err != nil {
+			return "", err
+		}
+		return sent.MessageID, nil
+
+	case models.QueuedActionStartChat:
+		if action.Target == "" {
+			if err := q.resolveRecipient(ctx, account, action); err != nil {
+				return "", err
+			}
+		}
+		started, err := q.unipile.StartChat(ctx, account.AccountID, action.Target, action.Payload.Text)
+		if err != nil {
+			return "", err
+		}
+		return started.ChatID, nil
+
+	case models.QueuedActionSendInvitation:
+		sent, err := q.unipile.SendInvitation(ctx, account.AccountID, action.Target, action.Payload.Note)
+		if err != nil {
+			return "", err
+		}
+
+		invitation := &models.Invitation{
+			LinkedAccountID:     account.ID,
+			InvitationID:        sent.InvitationID,
+			RecipientProviderID: action.Target,
+			RecipientIdentifier: action.Payload.RecipientIdentifier,
+			RecipientName:       action.Payload.RecipientName,
+			Note:                action.Payload.Note,
+			Status:              models.InvitationStatusPending,
+			SentAt:              time.Now(),
+		}
+		if err := q.invitations.Create(invitation); err != nil {
+			slog.ErrorContext(ctx, "Invitation sent but not stored", "invitation_id", sent.InvitationID, "error", err)
+		}
+		return sent.InvitationID, nil
+
+	default:
+		return "", fmt.Errorf("unknown action type %q", action.Type)
+	}
+}
+
+// resolveRecipient looks up the provider ID of a recipient only known by
+// public identifier, such as an imported lead. Fetching the profile counts
+// as a profile view.
+func (q *ActionQueue) resolveRecipient(ctx context.Context, account *models.LinkedAccount, action *models.QueuedAction) error {
+	cached, _, err := q.profiles.Get(ctx, account, action.Payload.RecipientIdentifier, false)
+	if err != nil {
+		return err
+	}
+
+	action.Target = cached.ProviderID
+	if action.Payload.RecipientName == "" {
+		action.Payload.RecipientName = cached.Profile.FullName
+	}
+	return nil
+}
+
+// reschedule puts an action back in the queue to run at the given time
+func (q *ActionQueue) reschedule(action *models.QueuedAction, at time.Time, reason string) {
+	action.Status = models.QueueStatusPending
+	action.ScheduledAt = at
+	action.LastError = reason
+	action.StartedAt = nil
+	if err := q.actions.Update(action); err != nil {
+		slog.Error("Failed to reschedule action", "action_id", action.ID, "error", err)
+	}
+}
+
+// fail marks an action as failed for good
+func (q *ActionQueue) fail(action *models.QueuedAction, reason string) {
+	slog.Warn("Queued action failed", "action_id", action.ID, "type", action.Type, "reason", reason)
+
+	now := time.Now()
+	action.Status = models.QueueStatusFailed
+	action.LastError = reason
+	action.CompletedAt = &now
+	if err := q.actions.Update(action); err != nil {
+		slog.Error("Failed to update action", "action_id", action.ID, "error", err)
+	}
+}
+
+// actionQuota returns the quota an action type counts against
+func actionQuota(actionType string) string {
+	if actionType == models.QueuedActionSendInvitation {
+		return models.ActionInvite
+	}
+	return models.ActionMessage
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/johnson7543/chatsheet-assessment/internal/config"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAccessTokenDuration  = 15 * time.Minute
+	defaultRefreshTokenDuration = 30 * 24 * time.Hour
+
+	// refreshTokenRetention is how long expired refresh tokens are kept
+	refreshTokenRetention = 24 * time.Hour
+
+	// refreshReuseGrace is how long after a refresh token was rotated it may
+	// be refreshed again, so concurrent refreshes (e.g. from two tabs) don't
+	// count as reuse
+	refreshReuseGrace = 10 * time.Second
+)
+
+// Refresh token errors
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token already used")
+)
+
+// TokenPair is an access token and the refresh token that replaces it
+type TokenPair struct {
+	AccessToken  string
+	ExpiresAt    time.Time
+	RefreshToken string
+}
+
+// TokenService issues short-lived access tokens and rotating refresh tokens.
+// Refresh tokens are only stored hashed. Every refresh replaces the token
+// with a new one of the same family; presenting a replaced token again, past
+// a short grace period, revokes the whole family, logging out whoever holds
+// it.
+type TokenService struct {
+	tokens     *repository.RefreshTokenRepository
+	users      *repository.UserRepository
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewTokenService creates a new token service. Access tokens are JWTs signed
+// with secret.
+func NewTokenService(tokens *repository.RefreshTokenRepository, users *repository.UserRepository, secret string, cfg config.JWTConfig) *TokenService {
+	accessTTL := cfg.AccessTokenDuration
+	if accessTTL <= 0 {
+		accessTTL = defaultAccessTokenDuration
+	}
+	refreshTTL := cfg.RefreshTokenDuration
+	if refreshTTL <= 0 {
+		refreshTTL = defaultRefreshTokenDuration
+	}
+
+	return &TokenService{
+		tokens:     tokens,
+		users:      users,
+		secret:     []byte(secret),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// Issue starts a new token family for a user who just logged in
+func (s *TokenService) Issue(ctx context.Context, user *models.User) (*TokenPair, error) {
+	if err := s.tokens.WithContext(ctx).DeleteExpired(time.Now().Add(-refreshTokenRetention)); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete expired refresh tokens", "error", err)
+	}
+
+	family, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, user, family)
+}
+
+// Refresh replaces a refresh token with a new pair. It fails with
+// ErrInvalidRefreshToken for unknown, expired or revoked tokens, and with
+// ErrRefreshTokenReused, after revoking the token's family, for tokens that
+// were already replaced. A token replaced less than refreshReuseGrace ago
+// gets another successor instead.
+func (s *TokenService) Refresh(ctx context.Context, token string) (*TokenPair, *models.User, error) {
+	tokens := s.tokens.WithContext(ctx)
+	stored, err := tokens.FindByHash(hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if stored.UsedAt != nil && !inReuseGrace(stored, now) {
+		s.revokeReused(ctx, stored, now)
+		return nil, nil, ErrRefreshTokenReused
+	}
+	if stored.RevokedAt != nil || now.After(stored.ExpiresAt) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	// Everything that can fail is done before the token is used up, so a
+	// failed refresh doesn't log the user out
+	user, err := s.users.WithContext(ctx).FindByID(stored.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	pair, successor, err := s.newPair(user, stored.FamilyID, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if stored.UsedAt == nil {
+		rotated, err := tokens.Rotate(stored.ID, successor, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		if rotated {
+			return pair, user, nil
+		}
+
+		// Another request rotated or revoked the token first
+		if stored, err = tokens.FindByHash(stored.TokenHash); err != nil {
+			return nil, nil, err
+		}
+		if stored.RevokedAt != nil {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		if !inReuseGrace(stored, now) {
+			s.revokeReused(ctx, stored, now)
+			return nil, nil, ErrRefreshTokenReused
+		}
+	}
+
+	// The token was just rotated by a concurrent refresh; give this one a
+	// successor of its own
+	if err := tokens.Create(successor); err != nil {
+		return nil, nil, err
+	}
+	return pair, user, nil
+}
+
+// Revoke revokes the family of a refresh token, ending its session. Unknown
+// tokens are ignored.
+func (s *TokenService) Revoke(ctx context.Context, token string) error {
+	tokens := s.tokens.WithContext(ctx)
+	stored, err := tokens.FindByHash(hashToken(token))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return tokens.RevokeFamily(stored.FamilyID, time.Now())
+}
+
+// issue creates an access token and a refresh token of the given family
+func (s *TokenService) issue(ctx context.Context, user *models.User, familyID string) (*TokenPair, error) {
+	pair, refresh, err := s.newPair(user, familyID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tokens.WithContext(ctx).Create(refresh); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// newPair signs an access token and generates a refresh token of the given
+// family, returning the refresh token's row without storing it
+func (s *TokenService) newPair(user *models.User, familyID string, now time.Time) (*TokenPair, *models.RefreshToken, error) {
+	expiresAt := now.Add(s.accessTTL)
+	access, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID,
+		"email":   user.Email,
+		"iat":     now.Unix(),
+		"exp":     expiresAt.Unix(),
+	}).SignedString(s.secret)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to sign access token: %v", err)
+	}
+
+	refresh, err := randomToken(32)
+	if err != nil {
+		return nil, nil, err
+	}
+	row := &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refresh),
+		ExpiresAt: now.Add(s.refreshTTL),
+	}
+	return &TokenPair{AccessToken: access, ExpiresAt: expiresAt, RefreshToken: refresh}, row, nil
+}
+
+// revokeReused revokes the family of a token presented after it was replaced
+func (s *TokenService) revokeReused(ctx context.Context, token *models.RefreshToken, now time.Time) {
+	slog.WarnContext(ctx, "Refresh token reused, revoking its family", "user_id", token.UserID, "token_id", token.ID)
+	if err := s.tokens.WithContext(ctx).RevokeFamily(token.FamilyID, now); err != nil {
+		slog.ErrorContext(ctx, "Failed to revoke refresh token family", "user_id", token.UserID, "error", err)
+	}
+}
+
+// inReuseGrace reports whether a used token was rotated recently enough to
+// be refreshed again
+func inReuseGrace(token *models.RefreshToken, now time.Time) bool {
+	return token.UsedAt != nil && token.RevokedAt == nil && now.Sub(*token.UsedAt) <= refreshReuseGrace
+}
+
+// randomToken returns n random bytes, base64url encoded
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the SHA-256 of a refresh token, as stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/config"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"gorm.io/gorm"
+)
+
+func TestTokenServiceRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare is given a token of a fresh family and returns the token
+		// to refresh
+		prepare           func(t *testing.T, s *TokenService, db *gorm.DB, token string) string
+		wantErr           error
+		wantFamilyRevoked bool
+	}{
+		{
+			name:    "rotation",
+			prepare: func(t *testing.T, s *TokenService, db *gorm.DB, token string) string { return token },
+		},
+		{
+			name: "unknown token",
+			prepare: func(t *testing.T, s *TokenService, db *gorm.DB, token string) string {
+				return "not-a-token"
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "replay within the grace period",
+			prepare: func(t *testing.T, s *TokenService, db *gorm.DB, token string) string {
+				mustRefresh(t, s, token)
+				return token
+			},
+		},
+		{
+			name: "replay after the grace period",
+			prepare: func(t *testing.T, s *TokenService, db *gorm.DB, token string) string {
+				mustRefresh(t, s, token)
+				setTokenColumn(t, db, token, "used_at", time.Now().Add(-refreshReuseGrace-time.Second))
+				return token
+			},
+			wantErr:           ErrRefreshTokenReused,
+			wantFamilyRevoked: true,
+		},
+		{
+			name: "expired",
+			prepare: func(t *testing.T, s *TokenService, db *gorm.DB, token string) string {
+				setTokenColumn(t, db, token, "expires_at", time.Now().Add(-time.Second))
+				return token
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "revoked",
+			prepare: func(t *testing.T, s *TokenService, db *gorm.DB, token string) string {
+				if err := s.Revoke(context.Background(), token); err != nil {
+					t.Fatalf("revoke: %v", err)
+				}
+				return token
+			},
+			wantErr:           ErrInvalidRefreshToken,
+			wantFamilyRevoked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := newTestDB(t, &models.User{}, &models.RefreshToken{})
+			users := repository.NewUserRepository(db)
+			s := NewTokenService(repository.NewRefreshTokenRepository(db), users, "secret", config.JWTConfig{})
+
+			user := &models.User{Email: "jane@example.com", Password: "x"}
+			if err := users.Create(user); err != nil {
+				t.Fatalf("create user: %v", err)
+			}
+			issued, err := s.Issue(ctx, user)
+			if err != nil {
+				t.Fatalf("issue: %v", err)
+			}
+			token := tt.prepare(t, s, db, issued.RefreshToken)
+			pair, got, err := s.Refresh(ctx, token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if got.ID != user.ID {
+					t.Errorf("user = %d, want %d", got.ID, user.ID)
+				}
+				if pair.RefreshToken == token || pair.AccessToken == "" {
+					t.Errorf("refresh returned pair %+v", pair)
+				}
+				if _, _, err := s.Refresh(ctx, pair.RefreshToken); err != nil {
+					t.Errorf("refresh with the new token: %v", err)
+				}
+			}
+
+			var active int64
+			if err := db.Model(&models.RefreshToken{}).Where("revoked_at IS NULL").Count(&active).Error; err != nil {
+				t.Fatalf("count tokens: %v", err)
+			}
+			if revoked := active == 0; revoked != tt.wantFamilyRevoked {
+				t.Errorf("family revoked = %v, want %v", revoked, tt.wantFamilyRevoked)
+			}
+		})
+	}
+}
+
+func mustRefresh(t *testing.T, s *TokenService, token string) *TokenPair {
+	t.Helper()
+	pair, _, err := s.Refresh(context.Background(), token)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	return pair
+}
+
+func setTokenColumn(t *testing.T, db *gorm.DB, token, column string, value interface{}) {
+	t.Helper()
+	if err := db.Model(&models.RefreshToken{}).Where("token_hash = ?", hashToken(token)).Update(column, value).Error; err != nil {
+		t.Fatalf("update %s: %v", column, err)
+	}
+}
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultAcceptanceTimeout is how long leads have to accept an invitation
+	// before a campaign gives up on them
+	defaultAcceptanceTimeout = 14 * 24 * time.Hour
+
+	// acceptancePollInterval is how often the relations of an account with
+	// leads waiting for acceptance are checked, in case a new_relation
+	// webhook was missed
+	acceptancePollInterval = 15 * time.Minute
+
+	// relationsPageSize and maxRelationPages bound one acceptance poll
+	relationsPageSize = 100
+	maxRelationPages  = 10
+)
+
+// CampaignEngine advances the leads of active campaigns through their steps.
+// Outbound steps go through the action queue; acceptance and replies are
+// read from the stored invitations and messages, which webhooks and the
+// message syncer keep up to date. Acceptances are also polled from the
+// account's relations, so campaigns don't depend on webhooks alone.
+type CampaignEngine struct {
+	unipile     UnipileClient
+	campaigns   *repository.CampaignRepository
+	accounts    *repository.LinkedAccountRepository
+	invitations *repository.InvitationRepository
+	messages    *repository.MessageRepository
+	actions     *repository.QueueRepository
+	queue       *ActionQueue
+	profiles    *ProfileService
+	interval    time.Duration
+
+	// acceptanceTimeout is how long after an invitation a message step waits
+	// for it to be accepted
+	acceptanceTimeout time.Duration
+
+	// polledAt is when the acceptances of each account were last polled. It
+	// is only used by the engine's goroutine.
+	polledAt map[uint]time.Time
+}
+
+// NewCampaignEngine creates a new campaign engine
+func NewCampaignEngine(unipile UnipileClient, campaigns *repository.CampaignRepository, accounts *repository.LinkedAccountRepository,
+	invitations *repository.InvitationRepository, messages *repository.MessageRepository, actions *repository.QueueRepository,
+	queue *ActionQueue, profiles *ProfileService, interval, acceptanceTimeout time.Duration) *CampaignEngine {
+	if acceptanceTimeout <= 0 {
+		acceptanceTimeout = defaultAcceptanceTimeout
+	}
+
+	return &CampaignEngine{
+		unipile:           unipile,
+		campaigns:         campaigns,
+		accounts:          accounts,
+		invitations:       invitations,
+		messages:          messages,
+		actions:           actions,
+		queue:             queue,
+		profiles:          profiles,
+		interval:          interval,
+		acceptanceTimeout: acceptanceTimeout,
+		polledAt:          make(map[uint]time.Time),
+	}
+}
+
+// Start advances campaigns immediately and then on every interval until ctx
+// is done. A non-positive interval disables the engine.
+func (e *CampaignEngine) Start(ctx context.Context) {
+	if e.interval <= 0 {
+		slog.Info("Campaign engine disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(e.interval)
+		defer ticker.Stop()
+
+		for {
+			e.AdvanceAll(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// AdvanceAll advances every lead of every active campaign
+func (e *CampaignEngine) AdvanceAll(ctx context.Context) {
+	campaigns, err := e.campaigns.FindByStatus(models.CampaignStatusActive)
+	if err != nil {
+		slog.Error("Failed to load active campaigns", "error", err)
+		return
+	}
+
+	for i := range campaigns {
+		if ctx.Err() != nil {
+			return
+		}
+		e.advanceCampaign(ctx, &campaigns[i])
+	}
+}
+
+// advanceCampaign advances the open leads of one campaign and completes the
+// campaign once none are left
+func (e *CampaignEngine) advanceCampaign(ctx context.Context, campaign *models.Campaign) {
+	account, err := e.accounts.FindByID(campaign.LinkedAccountID)
+	if err != nil {
+		slog.Warn("Pausing campaign: linked account not found", "campaign_id", campaign.ID, "account_id", campaign.LinkedAccountID)
+		e.setStatus(campaign, models.CampaignStatusPaused)
+		return
+	}
+	// Wait for disconnected accounts to be reconnected
+	if account.Status != models.AccountStatusOK {
+		return
+	}
+
+	leads, err := e.campaigns.FindLeads(campaign.ID, models.CampaignLeadActive)
+	if err != nil {
+		slog.Error("Failed to load leads of campaign", "campaign_id", campaign.ID, "error", err)
+		return
+	}
+
+	for i := range leads {
+		if leads[i].InvitedAt != nil && leads[i].AcceptedAt == nil {
+			e.pollAcceptances(ctx, account)
+			break
+		}
+	}
+
+	open := 0
+	for i := range leads {
+		if ctx.Err() != nil {
+			return
+		}
+		lead := &leads[i]
+		before := *lead
+		e.advanceLead(ctx, campaign, account, lead)
+		if !reflect.DeepEqual(before, *lead) {
+			if err := e.campaigns.UpdateLead(lead); err != nil {
+				slog.Error("Failed to update campaign lead", "campaign_lead_id", lead.ID, "error", err)
+			}
+		}
+		if lead.Status == models.CampaignLeadActive {
+			open++
+		}
+	}
+
+	if open == 0 {
+		e.setStatus(campaign, models.CampaignStatusCompleted)
+	}
+}
+
+// setStatus moves an active campaign to status. Only the status is written, and
+// only while the campaign is still active, so pauses and edits the user made
+// since the campaign was loaded are kept.
+func (e *CampaignEngine) setStatus(campaign *models.Campaign, status string) {
+	if _, err := e.campaigns.UpdateStatus(campaign.ID, models.CampaignStatusActive, status); err != nil {
+		slog.Error("Failed to update campaign status", "campaign_id", campaign.ID, "status", status, "error", err)
+	}
+}
+
+// pollAcceptances marks the account's pending invitations to members found
+// among its relations as accepted. Relations are read newest first, back to
+// the oldest pending invitation.
+func (e *CampaignEngine) pollAcceptances(ctx context.Context, account *models.LinkedAccount) {
+	now := time.Now()
+	if now.Sub(e.polledAt[account.ID]) < acceptancePollInterval {
+		return
+	}
+	e.polledAt[account.ID] = now
+
+	pending, err := e.invitations.FindByLinkedAccountID(account.ID, models.InvitationStatusPending)
+	if err != nil {
+		slog.Error("Failed to load pending invitations", "account_id", account.ID, "error", err)
+		return
+	}
+	if len(pending) == 0 {
+		return
+	}
+	invited := make(map[string]bool, len(pending))
+	for _, invitation := range pending {
+		invited[invitation.RecipientProviderID] = true
+	}
+	// Invitations are newest first
+	oldest := pending[len(pending)-1].SentAt
+
+	cursor := ""
+	for page := 0; page < maxRelationPages; page++ {
+		relations, err := e.unipile.ListRelations(ctx, account.AccountID, cursor, relationsPageSize)
+		if err != nil {
+			slog.Warn("Failed to poll relations of account", "account_id", account.ID, "error", err)
+			return
+		}
+
+		for _, relation := range relations.Items {
+			connectedAt := time.UnixMilli(relation.CreatedAt)
+			if connectedAt.Before(oldest) {
+				return
+			}
+			if !invited[relation.MemberID] {
+				continue
+			}
+			accepted, err := e.invitations.MarkAccepted(account.ID, relation.MemberID, connectedAt)
+			if err != nil {
+				slog.Error("Failed to mark invitation accepted", "account_id", account.ID, "error", err)
+				return
+			}
+			if accepted > 0 {
+				slog.Info("Invitation accepted, found by polling relations", "account_id", account.ID, "provider_id", relation.MemberID)
+			}
+		}
+
+		if relations.Cursor == "" {
+			return
+		}
+		cursor = relations.Cursor
+	}
+}
+
+// advanceLead records the outcome of the lead's action in flight, stops the
+// lead if they replied or declined, and otherwise runs its due steps
+func (e *CampaignEngine) advanceLead(ctx context.Context, campaign *models.Campaign, account *models.LinkedAccount, lead *models.CampaignLead) {
+	now := time.Now()
+
+	if lead.QueuedActionID != nil && !e.collectAction(lead) {
+		return
+	}
+
+	if lead.StartedAt != nil && lead.ProviderID != "" {
+		replied, err := e.messages.HasReply(account.ID, lead.ProviderID, lead.ChatID, *lead.StartedAt)
+		if err != nil {
+			slog.Error("Failed to check replies of campaign lead", "campaign_lead_id", lead.ID, "error", err)
+			return
+		}
+		if replied {
+			lead.Status, lead.RepliedAt = models.CampaignLeadReplied, &now
+			return
+		}
+	}
+
+	if lead.InvitedAt != nil && lead.AcceptedAt == nil {
+		invitation, err := e.invitations.FindLatestByRecipient(account.ID, lead.ProviderID)
+		if err == nil {
+			switch invitation.Status {
+			case models.InvitationStatusAccepted:
+				lead.AcceptedAt = invitation.AcceptedAt
+				if lead.AcceptedAt == nil {
+					lead.AcceptedAt = &now
+				}
+			case models.InvitationStatusWithdrawn:
+				lead.Status = models.CampaignLeadNotAccepted
+				return
+			}
+		}
+	}
+
+	for lead.QueuedActionID == nil && lead.Status == models.CampaignLeadActive && !now.Before(lead.NextActionAt) {
+		if lead.CurrentStep >= len(campaign.Steps) {
+			lead.Status = models.CampaignLeadCompleted
+			return
+		}
+
+		step := campaign.Steps[lead.CurrentStep]
+		switch step.Type {
+		case models.CampaignStepWait:
+			lead.CurrentStep++
+			lead.NextActionAt = now.AddDate(0, 0, step.Days)
+
+		case models.CampaignStepInvite, models.CampaignStepMessage:
+			// Messages can only be sent once the invitation is accepted
+			if step.Type == models.CampaignStepMessage && inviteBefore(campaign.Steps, lead.CurrentStep) && lead.AcceptedAt == nil {
+				if lead.InvitedAt != nil && now.After(lead.InvitedAt.Add(e.acceptanceTimeout)) {
+					lead.Status = models.CampaignLeadNotAccepted
+				}
+				return
+			}
+			if !e.resolveProfile(ctx, account, lead) {
+				return
+			}
+			e.enqueueStep(ctx, campaign, account, lead, step)
+			return
+		}
+	}
+}
+
+// collectAction records the outcome of the lead's action in flight and moves
+// the lead past its step. It returns false while the action is still queued
+// or when it failed.
+func (e *CampaignEngine) collectAction(lead *models.CampaignLead) bool {
+	action, err := e.actions.FindByID(*lead.QueuedActionID)
+	if err != nil {
+		lead.Status, lead.LastError = models.CampaignLeadFailed, "queued action not found"
+		return false
+	}
+
+	switch action.Status {
+	case models.QueueStatusDone:
+		completedAt := action.UpdatedAt
+		if action.CompletedAt != nil {
+			completedAt = *action.CompletedAt
+		}
+		switch action.Type {
+		case models.QueuedActionSendInvitation:
+			lead.InvitedAt = &completedAt
+		case models.QueuedActionStartChat:
+			lead.ChatID = action.ResultID
+			lead.MessagesSent++
+			lead.LastMessageAt = &completedAt
+		case models.QueuedActionSendMessage:
+			lead.MessagesSent++
+			lead.LastMessageAt = &completedAt
+		}
+		lead.QueuedActionID = nil
+		lead.CurrentStep++
+		lead.LastError = ""
+		return true
+
+	case models.QueueStatusFailed:
+		lead.Status, lead.LastError = models.CampaignLeadFailed, action.LastError
+	case models.QueueStatusCancelled:
+		lead.Status, lead.LastError = models.CampaignLeadFailed, "queued action cancelled"
+	}
+	return false
+}
+
+// resolveProfile looks up the lead's provider ID and name on first contact.
+// It returns false if the lead can't be contacted yet.
+func (e *CampaignEngine) resolveProfile(ctx context.Context, account *models.LinkedAccount, lead *models.CampaignLead) bool {
+	if lead.ProviderID != "" {
+		return true
+	}
+
+	cached, _, err := e.profiles.Get(ctx, account, lead.Identifier, false)
+	if err != nil {
+		var exceeded *QuotaExceededError
+		if errors.As(err, &exceeded) {
+			lead.NextActionAt = exceeded.ResetAt
+			lead.LastError = err.Error()
+			return false
+		}
+
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			lead.Status, lead.LastError = models.CampaignLeadFailed, "profile not found"
+			return false
+		}
+		// Try again on the next run
+		lead.LastError = err.Error()
+		return false
+	}
+
+	lead.ProviderID = cached.ProviderID
+	lead.Name = cached.Profile.FullName
+	return true
+}
+
+// enqueueStep queues the invitation or message of a step for the lead
+func (e *CampaignEngine) enqueueStep(ctx context.Context, campaign *models.Campaign, account *models.LinkedAccount, lead *models.CampaignLead, step models.CampaignStep) {
+	action := &models.QueuedAction{
+		UserID:          campaign.UserID,
+		LinkedAccountID: account.ID,
+		Payload: models.ActionPayload{
+			RecipientIdentifier: lead.Identifier,
+			RecipientName:       lead.Name,
+		},
+	}
+
+	switch {
+	case step.Type == models.CampaignStepInvite:
+		// Don't invite members who already have an invitation pending
+		if _, err := e.invitations.FindPendingByRecipient(account.ID, lead.ProviderID); err == nil {
+			lead.Status, lead.LastError = models.CampaignLeadFailed, "an invitation to this member is already pending"
+			return
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			lead.LastError = err.Error()
+			return
+		}
+		action.Type, action.Target, action.Payload.Note = models.QueuedActionSendInvitation, lead.ProviderID, step.Text
+	case lead.ChatID != "":
+		action.Type, action.Target, action.Payload.Text = models.QueuedActionSendMessage, lead.ChatID, step.Text
+	default:
+		action.Type, action.Target, action.Payload.Text = models.QueuedActionStartChat, lead.ProviderID, step.Text
+	}
+
+	if err := e.queue.Enqueue(ctx, action); err != nil {
+		slog.Error("Failed to queue campaign step", "step", lead.CurrentStep+1, "campaign_lead_id", lead.ID, "error", err)
+		lead.LastError = err.Error()
+		return
+	}
+
+	lead.QueuedActionID = &action.ID
+	if lead.StartedAt == nil {
+		startedAt := action.CreatedAt
+		lead.StartedAt = &startedAt
+	}
+}
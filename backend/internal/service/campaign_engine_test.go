@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+)
+
+// fakeRelations serves pages of relations, the cursor of each page being
+// its index. Calling any other UnipileClient method panics.
+type fakeRelations struct {
+	UnipileClient
+	pages []RelationList
+	err   error
+	calls int
+}
+
+func (f *fakeRelations) ListRelations(ctx context.Context, accountID, cursor string, limit int) (*RelationList, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	page := 0
+	if cursor != "" {
+		page = int(cursor[0] - '0')
+	}
+	return &f.pages[page], nil
+}
+
+func TestCampaignEnginePollAcceptances(t *testing.T) {
+	now := time.Now()
+	relation := func(memberID string, age time.Duration) Relation {
+		return Relation{MemberID: memberID, CreatedAt: now.Add(-age).UnixMilli()}
+	}
+
+	tests := []struct {
+		name string
+		// invited maps recipients of pending invitations to their age
+		invited      map[string]time.Duration
+		pages        []RelationList
+		err          error
+		wantAccepted []string
+		wantCalls    int
+	}{
+		{
+			name:    "accepted invitations across pages",
+			invited: map[string]time.Duration{"bob": 2 * time.Hour, "carol": 3 * time.Hour, "dave": 3 * time.Hour},
+			pages: []RelationList{
+				{Items: []Relation{relation("zoe", time.Minute), relation("bob", time.Hour)}, Cursor: "1"},
+				{Items: []Relation{relation("carol", 2*time.Hour)}},
+			},
+			wantAccepted: []string{"bob", "carol"},
+			wantCalls:    2,
+		},
+		{
+			name:    "stops at relations older than the oldest invitation",
+			invited: map[string]time.Duration{"bob": time.Hour},
+			pages: []RelationList{
+				{Items: []Relation{relation("zoe", time.Minute), relation("amy", 2*time.Hour)}, Cursor: "1"},
+				{Items: []Relation{relation("bob", 3*time.Hour)}},
+			},
+			wantCalls: 1,
+		},
+		{
+			name:      "no pending invitations",
+			wantCalls: 0,
+		},
+		{
+			name:      "Unipile unavailable",
+			invited:   map[string]time.Duration{"bob": time.Hour},
+			err:       &APIError{StatusCode: 503},
+			wantCalls: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t, &models.Invitation{})
+			invitations := repository.NewInvitationRepository(db)
+			for recipient, age := range tt.invited {
+				if err := invitations.Create(&models.Invitation{
+					LinkedAccountID:     1,
+					RecipientProviderID: recipient,
+					Status:              models.InvitationStatusPending,
+					SentAt:              now.Add(-age),
+				}); err != nil {
+					t.Fatalf("create invitation: %v", err)
+				}
+			}
+
+			unipile := &fakeRelations{pages: tt.pages, err: tt.err}
+			e := NewCampaignEngine(unipile, nil, nil, invitations, nil, nil, nil, nil, time.Minute, 0)
+			account := &models.LinkedAccount{ID: 1, AccountID: "acc_1"}
+			e.pollAcceptances(context.Background(), account)
+
+			if unipile.calls != tt.wantCalls {
+				t.Errorf("%d calls to Unipile, want %d", unipile.calls, tt.wantCalls)
+			}
+			accepted, err := invitations.FindByLinkedAccountID(1, models.InvitationStatusAccepted)
+			if err != nil {
+				t.Fatalf("find invitations: %v", err)
+			}
+			var got []string
+			for _, invitation := range accepted {
+				got = append(got, invitation.RecipientProviderID)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.wantAccepted) {
+				t.Errorf("accepted = %v, want %v", got, tt.wantAccepted)
+			}
+
+			// Polls are spaced out
+			calls := unipile.calls
+			e.pollAcceptances(context.Background(), account)
+			if unipile.calls != calls {
+				t.Errorf("polled again within %v", acceptancePollInterval)
+			}
+		})
+	}
+}
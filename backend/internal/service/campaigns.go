@@ -0,0 +1,76 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+)
+
+// ValidateCampaignSteps checks that a campaign's steps can be run: waits last
+// at least a day, messages have text, and there is at most one invitation,
+// whose note fits LinkedIn's limit
+func ValidateCampaignSteps(steps []models.CampaignStep) error {
+	invites := 0
+	for i, step := range steps {
+		switch step.Type {
+		case models.CampaignStepInvite:
+			invites++
+			if invites > 1 {
+				return fmt.Errorf("step %d: a campaign can send only one invitation", i+1)
+			}
+			if len([]rune(step.Text)) > models.InvitationNoteMaxLength {
+				return fmt.Errorf("step %d: invitation note exceeds %d characters", i+1, models.InvitationNoteMaxLength)
+			}
+		case models.CampaignStepWait:
+			if step.Days < 1 {
+				return fmt.Errorf("step %d: wait must last at least 1 day", i+1)
+			}
+		case models.CampaignStepMessage:
+			if step.Text == "" {
+				return fmt.Errorf("step %d: message text is required", i+1)
+			}
+		default:
+			return fmt.Errorf("step %d: unknown step type %q", i+1, step.Type)
+		}
+	}
+	return nil
+}
+
+// NewCampaignLeads builds the leads of a campaign from profile URLs, skipping
+// duplicates. Entries that aren't LinkedIn profile URLs are returned as invalid.
+func NewCampaignLeads(campaignID uint, profileURLs []string) (leads []models.CampaignLead, invalid []string) {
+	now := time.Now()
+	seen := make(map[string]bool)
+	for _, profileURL := range profileURLs {
+		identifier, ok := ParseProfileIdentifier(profileURL)
+		if !ok {
+			invalid = append(invalid, profileURL)
+			continue
+		}
+		if seen[identifier] {
+			continue
+		}
+		seen[identifier] = true
+
+		leads = append(leads, models.CampaignLead{
+			CampaignID:   campaignID,
+			Identifier:   identifier,
+			ProfileURL:   profileURL,
+			Status:       models.CampaignLeadActive,
+			NextActionAt: now,
+		})
+	}
+	return leads, invalid
+}
+
+// inviteBefore reports whether a campaign sends an invitation before the
+// given step
+func inviteBefore(steps []models.CampaignStep, index int) bool {
+	for _, step := range steps[:index] {
+		if step.Type == models.CampaignStepInvite {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Export formats
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// Spreadsheet limits of XLSX files
+const (
+	xlsxMaxRows       = 1 << 20
+	xlsxMaxCellLength = 32767
+)
+
+// ErrExportTooManyRows is returned when an export doesn't fit in a worksheet
+var ErrExportTooManyRows = errors.New("too many rows for an XLSX worksheet")
+
+// ExportWriter writes the rows of an export as they are read, so exports
+// never hold the whole table in memory
+type ExportWriter interface {
+	WriteRow(values []string) error
+	// Flush sends buffered rows to the underlying writer
+	Flush() error
+	// Close finishes the file. It doesn't close the underlying writer.
+	Close() error
+}
+
+// NewExportWriter creates a writer for the given format
+func NewExportWriter(format string, w io.Writer, sheet string) (ExportWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		return &csvExportWriter{w: csv.NewWriter(w)}, nil
+	case ExportFormatXLSX:
+		return newXLSXExportWriter(w, sheet)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// ExportContentType returns the MIME type of an export format
+func ExportContentType(format string) string {
+	if format == ExportFormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+type csvExportWriter struct {
+	w *csv.Writer
+}
+
+func (e *csvExportWriter) WriteRow(values []string) error {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = escapeFormula(value)
+	}
+	return e.w.Write(escaped)
+}
+
+func (e *csvExportWriter) Flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExportWriter) Close() error {
+	return e.Flush()
+}
+
+// escapeFormula stops spreadsheet apps from running cell values, such as
+// message text written by other LinkedIn members, as formulas
+func escapeFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// xlsxExportWriter streams a single-sheet XLSX workbook. Cells are inline
+// strings, so no shared string table has to be built up front.
+type xlsxExportWriter struct {
+	zip   *zip.Writer
+	sheet io.Writer
+	rows  int
+}
+
+// xlsxParts are the fixed parts of the workbook, written before the sheet
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+func newXLSXExportWriter(w io.Writer, sheet string) (*xlsxExportWriter, error) {
+	e := &xlsxExportWriter{zip: zip.NewWriter(w)}
+
+	var name strings.Builder
+	if err := xml.EscapeText(&name, []byte(sheet)); err != nil {
+		return nil, err
+	}
+	workbook := xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="` + name.String() + `" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+	for _, part := range xlsxParts {
+		if err := e.writePart(part.name, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := e.writePart("xl/workbook.xml", workbook); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if e.sheet, err = e.zip.Create("xl/worksheets/sheet1.xml"); err != nil {
+		return nil, err
+	}
+	_, err = io.WriteString(e.sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return e, err
+}
+
+func (e *xlsxExportWriter) writePart(name, content string) error {
+	f, err := e.zip.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, content)
+	return err
+}
+
+func (e *xlsxExportWriter) WriteRow(values []string) error {
+	if e.rows == xlsxMaxRows {
+		return ErrExportTooManyRows
+	}
+	e.rows++
+
+	var row strings.Builder
+	fmt.Fprintf(&row, `<row r="%d">`, e.rows)
+	for i, value := range values {
+		if value == "" {
+			continue
+		}
+		if len(value) > xlsxMaxCellLength {
+			value = truncateUTF8(value, xlsxMaxCellLength)
+		}
+		// Empty cells are left out, so each cell names its position
+		fmt.Fprintf(&row, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">`, xlsxColumn(i), e.rows)
+		// EscapeText also replaces characters XML can't hold
+		if err := xml.EscapeText(&row, []byte(value)); err != nil {
+			return err
+		}
+		row.WriteString(`</t></is></c>`)
+	}
+	row.WriteString(`</row>`)
+
+	_, err := io.WriteString(e.sheet, row.String())
+	return err
+}
+
+func (e *xlsxExportWriter) Flush() error {
+	return e.zip.Flush()
+}
+
+func (e *xlsxExportWriter) Close() error {
+	if _, err := io.WriteString(e.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return e.zip.Close()
+}
+
+// xlsxColumn returns the letters of a zero-based column index: A, B, ..., Z,
+// AA, AB, ...
+func xlsxColumn(i int) string {
+	var name []byte
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = append([]byte{byte('A' + (i-1)%26)}, name...)
+	}
+	return string(name)
+}
+
+// truncateUTF8 shortens s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestXLSXExportWriter(t *testing.T) {
+	long := strings.Repeat("x", xlsxMaxCellLength-1) + "é"
+
+	tests := []struct {
+		name string
+		rows [][]string
+		want []string
+	}{
+		{
+			name: "cells name their position",
+			rows: [][]string{{"Name", "Company"}, {"Jane", "Acme"}},
+			want: []string{
+				`<row r="1"><c r="A1" t="inlineStr"><is><t xml:space="preserve">Name</t></is></c><c r="B1" t="inlineStr"><is><t xml:space="preserve">Company</t></is></c></row>`,
+				`<c r="B2" t="inlineStr"><is><t xml:space="preserve">Acme</t></is></c></row>`,
+			},
+		},
+		{
+			name: "empty cells are left out",
+			rows: [][]string{{"", "b", ""}, {}},
+			want: []string{
+				`<row r="1"><c r="B1" t="inlineStr"><is><t xml:space="preserve">b</t></is></c></row><row r="2"></row>`,
+			},
+		},
+		{
+			name: "XML special characters are escaped",
+			rows: [][]string{{`<b>"Tom" & 'Jerry'</b>`, "bell\a"}},
+			want: []string{
+				`<t xml:space="preserve">&lt;b&gt;&#34;Tom&#34; &amp; &#39;Jerry&#39;&lt;/b&gt;</t>`,
+				"<t xml:space=\"preserve\">bell\uFFFD</t>",
+			},
+		},
+		{
+			name: "long cells are truncated without splitting characters",
+			rows: [][]string{{long}},
+			want: []string{`<t xml:space="preserve">` + strings.Repeat("x", xlsxMaxCellLength-1) + `</t>`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewExportWriter(ExportFormatXLSX, &buf, "Leads & Co")
+			if err != nil {
+				t.Fatalf("new writer: %v", err)
+			}
+			for _, row := range tt.rows {
+				if err := w.WriteRow(row); err != nil {
+					t.Fatalf("write row: %v", err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			workbook := readZipFile(t, buf.Bytes(), "xl/workbook.xml")
+			if !strings.Contains(workbook, `<sheet name="Leads &amp; Co"`) {
+				t.Errorf("workbook = %s, want the escaped sheet name", workbook)
+			}
+			sheet := readZipFile(t, buf.Bytes(), "xl/worksheets/sheet1.xml")
+			if !strings.HasSuffix(sheet, `</sheetData></worksheet>`) {
+				t.Errorf("sheet isn't closed: %.200s", sheet)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(sheet, want) {
+					t.Errorf("sheet = %.500s, want it to contain %.200s", sheet, want)
+				}
+			}
+		})
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "A"},
+		{1, "B"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+
+	for _, tt := range tests {
+		if got := xlsxColumn(tt.index); got != tt.want {
+			t.Errorf("xlsxColumn(%d) = %q, want %q", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", ""},
+		{"Jane", "Jane"},
+		{"=HYPERLINK(\"x\")", "'=HYPERLINK(\"x\")"},
+		{"+1 555", "'+1 555"},
+		{"-5", "'-5"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"a=b", "a=b"},
+	}
+
+	for _, tt := range tests {
+		if got := escapeFormula(tt.value); got != tt.want {
+			t.Errorf("escapeFormula(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+// readZipFile returns the content of a file in a zip archive
+func readZipFile(t *testing.T, archive []byte, name string) string {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("open archive: %v", err)
+	}
+	f, err := r.Open(name)
+	if err != nil {
+		t.Fatalf("open %s: %v", name, err)
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(content)
+}
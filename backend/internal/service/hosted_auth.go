@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+)
+
+const (
+	defaultHostedAuthLinkTTL = 15 * time.Minute
+
+	// hostedAuthSessionRetention is how long expired sessions are kept
+	hostedAuthSessionRetention = 24 * time.Hour
+
+	// hostedAuthStateContext and hostedAuthNotifyContext keep hosted auth
+	// states and notify keys from being valid as any other value signed with
+	// the same secret
+	hostedAuthStateContext  = "hosted-auth:"
+	hostedAuthNotifyContext = "hosted-auth-notify:"
+)
+
+// Hosted auth state errors
+var (
+	ErrInvalidHostedAuthState = errors.New("invalid hosted auth state")
+	ErrHostedAuthStateExpired = errors.New("hosted auth link expired")
+	ErrHostedAuthStateUsed    = errors.New("hosted auth link already used")
+	ErrHostedAuthDisabled     = errors.New("hosted auth is not configured")
+)
+
+// HostedAuthState identifies the user a hosted auth link was created for. It
+// is sent to Unipile as the link's name, signed, and comes back with the
+// notify callback.
+type HostedAuthState struct {
+	UserID    uint
+	Nonce     string
+	ExpiresAt time.Time
+}
+
+// HostedAuthService creates Unipile hosted auth links and verifies their
+// callbacks. A state is only accepted if its signature matches, it hasn't
+// expired, and its session hasn't been used yet. As the state travels through
+// the user's browser, callbacks must also carry the link's notify key, which
+// is only ever sent to Unipile.
+type HostedAuthService struct {
+	unipile            UnipileClient
+	sessions           *repository.HostedAuthRepository
+	secret             []byte
+	ttl                time.Duration
+	notifyURL          string
+	successRedirectURL string
+	failureRedirectURL string
+}
+
+// NewHostedAuthService creates a new hosted auth service. States are signed
+// with secret; notifyURL is the public URL of the notify callback and
+// frontendURL where users land after the wizard. Without a notifyURL, links
+// can't be created.
+func NewHostedAuthService(unipile UnipileClient, sessions *repository.HostedAuthRepository, secret string, ttl time.Duration, notifyURL, frontendURL string) *HostedAuthService {
+	if ttl <= 0 {
+		ttl = defaultHostedAuthLinkTTL
+	}
+	frontendURL = strings.TrimRight(frontendURL, "/")
+
+	return &HostedAuthService{
+		unipile:            unipile,
+		sessions:           sessions,
+		secret:             []byte(secret),
+		ttl:                ttl,
+		notifyURL:          notifyURL,
+		successRedirectURL: frontendURL + "/?hosted_auth=success",
+		failureRedirectURL: frontendURL + "/?hosted_auth=failure",
+	}
+}
+
+// CreateLink creates a hosted auth link for connecting a LinkedIn account of
+// the user
+func (s *HostedAuthService) CreateLink(ctx context.Context, userID uint) (*HostedAuthLink, time.Time, error) {
+	if s.notifyURL == "" {
+		return nil, time.Time{}, ErrHostedAuthDisabled
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, time.Time{}, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	state := HostedAuthState{
+		UserID:    userID,
+		Nonce:     hex.EncodeToString(nonce),
+		ExpiresAt: time.Now().Add(s.ttl).Truncate(time.Second),
+	}
+	session := &models.HostedAuthSession{
+		UserID:    userID,
+		Nonce:     state.Nonce,
+		ExpiresAt: state.ExpiresAt,
+	}
+	if err := s.sessions.WithContext(ctx).Create(session); err != nil {
+		return nil, time.Time{}, err
+	}
+	if err := s.sessions.WithContext(ctx).DeleteExpired(time.Now().Add(-hostedAuthSessionRetention)); err != nil {
+		slog.ErrorContext(ctx, "Failed to delete expired hosted auth sessions", "error", err)
+	}
+
+	link, err := s.unipile.CreateHostedAuthLink(ctx, HostedAuthLinkRequest{
+		Type:               "create",
+		Providers:          []string{LinkedIn.UnipileType},
+		ExpiresOn:          state.ExpiresAt.UTC(),
+		NotifyURL:          s.notifyURL + "?" + url.Values{"key": {s.NotifyKey(&state)}}.Encode(),
+		Name:               s.Sign(state),
+		SuccessRedirectURL: s.successRedirectURL,
+		FailureRedirectURL: s.failureRedirectURL,
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return link, state.ExpiresAt, nil
+}
+
+// Consume verifies the state of a notify callback and marks its session used
+// by the connected account. It fails with ErrInvalidHostedAuthState,
+// ErrHostedAuthStateExpired or ErrHostedAuthStateUsed.
+func (s *HostedAuthService) Consume(ctx context.Context, token, accountID string) (*HostedAuthState, error) {
+	state, err := s.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.After(state.ExpiresAt) {
+		return nil, ErrHostedAuthStateExpired
+	}
+
+	consumed, err := s.sessions.WithContext(ctx).Consume(state.UserID, state.Nonce, accountID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrHostedAuthStateUsed
+	}
+	return state, nil
+}
+
+// Sign encodes a state as "<user ID>.<nonce>.<expiry>.<signature>"
+func (s *HostedAuthService) Sign(state HostedAuthState) string {
+	payload := fmt.Sprintf("%d.%s.%d", state.UserID, state.Nonce, state.ExpiresAt.Unix())
+	return payload + "." + s.signature(payload)
+}
+
+// Verify decodes a signed state, checking its signature but not its expiry
+func (s *HostedAuthService) Verify(token string) (*HostedAuthState, error) {
+	i := strings.LastIndexByte(token, '.')
+	if i < 0 {
+		return nil, ErrInvalidHostedAuthState
+	}
+	payload, signature := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(s.signature(payload))) {
+		return nil, ErrInvalidHostedAuthState
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidHostedAuthState
+	}
+	userID, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidHostedAuthState
+	}
+	expiresAt, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidHostedAuthState
+	}
+
+	return &HostedAuthState{
+		UserID:    uint(userID),
+		Nonce:     parts[1],
+		ExpiresAt: time.Unix(expiresAt, 0),
+	}, nil
+}
+
+// NotifyKey returns the key carried by the notify URL of a state's link
+func (s *HostedAuthService) NotifyKey(state *HostedAuthState) string {
+	return s.mac(hostedAuthNotifyContext + state.Nonce)
+}
+
+// VerifyNotifyKey reports whether key is the notify key of state's link
+func (s *HostedAuthService) VerifyNotifyKey(state *HostedAuthState, key string) bool {
+	return hmac.Equal([]byte(key), []byte(s.NotifyKey(state)))
+}
+
+func (s *HostedAuthService) signature(payload string) string {
+	return s.mac(hostedAuthStateContext + payload)
+}
+
+func (s *HostedAuthService) mac(value string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
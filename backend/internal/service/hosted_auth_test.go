@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+)
+
+// fakeUnipile records the hosted auth link requested from it. Calling any
+// other UnipileClient method panics.
+type fakeUnipile struct {
+	UnipileClient
+	link HostedAuthLinkRequest
+}
+
+func (f *fakeUnipile) CreateHostedAuthLink(ctx context.Context, req HostedAuthLinkRequest) (*HostedAuthLink, error) {
+	f.link = req
+	return &HostedAuthLink{Object: "HostedAuthUrl", URL: "https://account.unipile.com/link"}, nil
+}
+
+func TestHostedAuthServiceVerify(t *testing.T) {
+	s := NewHostedAuthService(nil, nil, "secret", 0, "", "")
+	state := HostedAuthState{UserID: 42, Nonce: "abc123", ExpiresAt: time.Unix(1700000000, 0)}
+	token := s.Sign(state)
+	dot := strings.LastIndexByte(token, '.')
+	payload, signature := token[:dot], token[dot+1:]
+
+	tests := []struct {
+		name  string
+		token string
+		want  *HostedAuthState
+	}{
+		{name: "valid", token: token, want: &state},
+		{name: "tampered signature", token: payload + "." + strings.ToUpper(signature)},
+		{name: "tampered payload", token: strings.Replace(token, "42.", "43.", 1)},
+		{name: "signed with another secret", token: NewHostedAuthService(nil, nil, "other", 0, "", "").Sign(state)},
+		{name: "no signature", token: "42"},
+		{name: "wrong number of parts", token: "42.abc123." + s.signature("42.abc123")},
+		{name: "non-numeric user ID", token: "me.abc123.1700000000." + s.signature("me.abc123.1700000000")},
+		{name: "non-numeric expiry", token: "42.abc123.soon." + s.signature("42.abc123.soon")},
+		{name: "empty", token: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Verify(tt.token)
+			if tt.want == nil {
+				if !errors.Is(err, ErrInvalidHostedAuthState) {
+					t.Fatalf("error = %v, want ErrInvalidHostedAuthState", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.UserID != tt.want.UserID || got.Nonce != tt.want.Nonce || !got.ExpiresAt.Equal(tt.want.ExpiresAt) {
+				t.Errorf("state = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHostedAuthServiceCreateLink(t *testing.T) {
+	tests := []struct {
+		name      string
+		notifyURL string
+		wantErr   error
+	}{
+		{name: "link", notifyURL: "https://api.example.com/api/linkedin/hosted-auth/notify"},
+		{name: "no notify URL", wantErr: ErrHostedAuthDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := newTestDB(t, &models.HostedAuthSession{})
+			unipile := &fakeUnipile{}
+			s := NewHostedAuthService(unipile, repository.NewHostedAuthRepository(db), "secret", time.Minute, tt.notifyURL, "https://app.example.com/")
+
+			link, expiresAt, err := s.CreateLink(ctx, 7)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if link.URL == "" {
+				t.Errorf("link has no URL")
+			}
+
+			req := unipile.link
+			if req.SuccessRedirectURL != "https://app.example.com/?hosted_auth=success" {
+				t.Errorf("success redirect = %q", req.SuccessRedirectURL)
+			}
+			state, err := s.Verify(req.Name)
+			if err != nil {
+				t.Fatalf("verify link name: %v", err)
+			}
+			if state.UserID != 7 || !state.ExpiresAt.Equal(expiresAt) {
+				t.Errorf("state = %+v, want user 7 expiring at %v", state, expiresAt)
+			}
+
+			notify, err := url.Parse(req.NotifyURL)
+			if err != nil {
+				t.Fatalf("parse notify URL: %v", err)
+			}
+			if base := notify.Scheme + "://" + notify.Host + notify.Path; base != tt.notifyURL {
+				t.Errorf("notify URL = %q, want %q with a key", req.NotifyURL, tt.notifyURL)
+			}
+			if !s.VerifyNotifyKey(state, notify.Query().Get("key")) {
+				t.Errorf("notify key %q doesn't verify", notify.Query().Get("key"))
+			}
+			if s.VerifyNotifyKey(&HostedAuthState{UserID: 7, Nonce: "other"}, notify.Query().Get("key")) {
+				t.Errorf("notify key verifies for another link")
+			}
+
+			// The link can be used once
+			if _, err := s.Consume(ctx, req.Name, "acc_1"); err != nil {
+				t.Fatalf("consume: %v", err)
+			}
+			if _, err := s.Consume(ctx, req.Name, "acc_2"); !errors.Is(err, ErrHostedAuthStateUsed) {
+				t.Errorf("second consume error = %v, want ErrHostedAuthStateUsed", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+)
+
+// MaxLeadImportRows caps the data rows of one lead import
+const MaxLeadImportRows = 10000
+
+// ErrTooManyRows is returned when a lead import exceeds MaxLeadImportRows
+var ErrTooManyRows = fmt.Errorf("the file has more than %d rows", MaxLeadImportRows)
+
+// Column names recognized for lead fields without an explicit mapping,
+// compared after normalizeColumn
+var leadColumnAliases = map[string][]string{
+	"profile_url": {"profile_url", "linkedin_url", "linkedin", "linkedin_profile", "profile", "url"},
+	"first_name":  {"first_name", "firstname", "given_name"},
+	"last_name":   {"last_name", "lastname", "surname", "family_name"},
+	"full_name":   {"full_name", "name"},
+	"company":     {"company", "company_name", "organization", "account"},
+}
+
+// LeadImport is the outcome of parsing a lead CSV
+type LeadImport struct {
+	Leads      []models.Lead
+	Rows       int
+	Duplicates int
+	Errors     []models.LeadImportError
+}
+
+// ParseLeadsCSV reads leads from a CSV with a header row. Rows without a
+// valid LinkedIn profile URL are reported as errors; repeated profiles keep
+// their first row. The returned error is for problems with the file as a
+// whole, such as a mapping naming a missing column.
+func ParseLeadsCSV(r io.Reader, mapping models.LeadColumnMapping) (*LeadImport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(header) > 0 {
+		// Spreadsheet exports often start with a byte order mark
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	columns, err := resolveLeadColumns(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &LeadImport{Errors: []models.LeadImportError{}}
+	seen := make(map[string]bool)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("invalid CSV: %w", err)
+			}
+			result.Rows++
+			result.Errors = append(result.Errors, models.LeadImportError{Row: parseErr.StartLine, Error: parseErr.Err.Error()})
+			continue
+		}
+		if isBlankRecord(record) {
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		result.Rows++
+		if result.Rows > MaxLeadImportRows {
+			return nil, ErrTooManyRows
+		}
+
+		lead, err := columns.lead(record)
+		if err != nil {
+			result.Errors = append(result.Errors, models.LeadImportError{Row: line, Error: err.Error()})
+			continue
+		}
+		if seen[lead.PublicIdentifier] {
+			result.Duplicates++
+			continue
+		}
+		seen[lead.PublicIdentifier] = true
+		result.Leads = append(result.Leads, *lead)
+	}
+
+	return result, nil
+}
+
+// NormalizeLeadProfile checks that input is a LinkedIn profile URL and
+// returns its lowercased public identifier and canonical URL, so the same
+// profile written differently is recognized
+func NormalizeLeadProfile(input string) (identifier, profileURL string, ok bool) {
+	if !strings.Contains(input, "linkedin.com/") {
+		return "", "", false
+	}
+	identifier, ok = ParseProfileIdentifier(input)
+	if !ok {
+		return "", "", false
+	}
+	identifier = strings.ToLower(identifier)
+	return identifier, "https://www.linkedin.com/in/" + url.PathEscape(identifier) + "/", true
+}
+
+// LeadTemplateData builds template variables from a lead's imported fields
+func LeadTemplateData(lead *models.Lead) models.TemplateData {
+	return models.TemplateData{
+		FirstName:        lead.FirstName,
+		LastName:         lead.LastName,
+		FullName:         strings.TrimSpace(lead.FirstName + " " + lead.LastName),
+		Company:          lead.Company,
+		PublicIdentifier: lead.PublicIdentifier,
+		Custom:           lead.CustomFields,
+	}
+}
+
+// MergeLead copies the non-empty fields of an imported lead onto an existing
+// one, so re-importing a sheet with blank cells doesn't erase data
+func MergeLead(existing, imported *models.Lead) {
+	for _, field := range []struct{ dst, src *string }{
+		{&existing.FirstName, &imported.FirstName},
+		{&existing.LastName, &imported.LastName},
+		{&existing.Company, &imported.Company},
+	} {
+		if *field.src != "" {
+			*field.dst = *field.src
+		}
+	}
+
+	for key, value := range imported.CustomFields {
+		if value == "" {
+			continue
+		}
+		if existing.CustomFields == nil {
+			existing.CustomFields = make(map[string]string)
+		}
+		existing.CustomFields[key] = value
+	}
+}
+
+// leadColumns holds the column index of each lead field, -1 when absent
+type leadColumns struct {
+	profileURL, firstName, lastName, fullName, company int
+	custom                                             map[string]int
+}
+
+// resolveLeadColumns finds the columns of each lead field in the header
+func resolveLeadColumns(header []string, mapping models.LeadColumnMapping) (*leadColumns, error) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		if _, ok := index[normalizeColumn(name)]; !ok {
+			index[normalizeColumn(name)] = i
+		}
+	}
+
+	used := make(map[int]bool)
+	find := func(field, column string) (int, error) {
+		if column != "" {
+			i, ok := index[normalizeColumn(column)]
+			if !ok {
+				return -1, fmt.Errorf("column %q not found", column)
+			}
+			used[i] = true
+			return i, nil
+		}
+		for _, alias := range leadColumnAliases[field] {
+			if i, ok := index[alias]; ok {
+				used[i] = true
+				return i, nil
+			}
+		}
+		return -1, nil
+	}
+
+	var columns leadColumns
+	var err error
+	for _, field := range []struct {
+		name, column string
+		dst          *int
+	}{
+		{"profile_url", mapping.ProfileURL, &columns.profileURL},
+		{"first_name", mapping.FirstName, &columns.firstName},
+		{"last_name", mapping.LastName, &columns.lastName},
+		{"full_name", mapping.FullName, &columns.fullName},
+		{"company", mapping.Company, &columns.company},
+	} {
+		if *field.dst, err = find(field.name, field.column); err != nil {
+			return nil, err
+		}
+	}
+	if columns.profileURL < 0 {
+		return nil, errors.New("no LinkedIn profile URL column; map one with profile_url")
+	}
+
+	columns.custom = make(map[string]int)
+	if mapping.CustomFields != nil {
+		for name, column := range mapping.CustomFields {
+			i, ok := index[normalizeColumn(column)]
+			if !ok {
+				return nil, fmt.Errorf("column %q not found", column)
+			}
+			columns.custom[name] = i
+		}
+		return &columns, nil
+	}
+	for i, name := range header {
+		if key := normalizeColumn(name); !used[i] && key != "" {
+			columns.custom[key] = i
+		}
+	}
+	return &columns, nil
+}
+
+// lead builds a lead from one CSV record
+func (c *leadColumns) lead(record []string) (*models.Lead, error) {
+	cell := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	input := cell(c.profileURL)
+	if input == "" {
+		return nil, errors.New("missing LinkedIn profile URL")
+	}
+	identifier, profileURL, ok := NormalizeLeadProfile(input)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a LinkedIn profile URL", input)
+	}
+
+	lead := &models.Lead{
+		PublicIdentifier: identifier,
+		ProfileURL:       profileURL,
+		FirstName:        cell(c.firstName),
+		LastName:         cell(c.lastName),
+		Company:          cell(c.company),
+	}
+	if lead.FirstName == "" && lead.LastName == "" {
+		first, last, _ := strings.Cut(cell(c.fullName), " ")
+		lead.FirstName, lead.LastName = first, strings.TrimSpace(last)
+	}
+
+	for name, i := range c.custom {
+		if value := cell(i); value != "" {
+			if lead.CustomFields == nil {
+				lead.CustomFields = make(map[string]string)
+			}
+			lead.CustomFields[name] = value
+		}
+	}
+	return lead, nil
+}
+
+// normalizeColumn turns a column header into a snake_case key, so "First
+// Name" matches first_name and custom fields work as template variables
+func normalizeColumn(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == ' ' || r == '_' || r == '-' || r == '.'
+	})
+	return strings.Join(fields, "_")
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
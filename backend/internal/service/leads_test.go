@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+)
+
+func TestParseLeadsCSV(t *testing.T) {
+	tests := []struct {
+		name           string
+		csv            string
+		mapping        models.LeadColumnMapping
+		wantLeads      []models.Lead
+		wantRows       int
+		wantDuplicates int
+		wantErrorRows  []int
+		wantErr        string
+	}{
+		{
+			name: "aliased columns and custom fields",
+			csv: "\ufeffLinkedIn URL,First Name,Last Name,Company Name,Job Title\n" +
+				"https://www.linkedin.com/in/Jane-Doe/,Jane,Doe,Acme,VP Sales\n",
+			wantLeads: []models.Lead{{
+				PublicIdentifier: "jane-doe",
+				ProfileURL:       "https://www.linkedin.com/in/jane-doe/",
+				FirstName:        "Jane",
+				LastName:         "Doe",
+				Company:          "Acme",
+				CustomFields:     map[string]string{"job_title": "VP Sales"},
+			}},
+			wantRows: 1,
+		},
+		{
+			name: "full name is split",
+			csv:  "profile,name\nlinkedin.com/in/bob,Bob van Dijk\n",
+			wantLeads: []models.Lead{{
+				PublicIdentifier: "bob",
+				ProfileURL:       "https://www.linkedin.com/in/bob/",
+				FirstName:        "Bob",
+				LastName:         "van Dijk",
+			}},
+			wantRows: 1,
+		},
+		{
+			name:    "explicit mapping",
+			csv:     "Who,Where,Sector\nhttps://linkedin.com/in/amy,Initech,Software\n",
+			mapping: models.LeadColumnMapping{ProfileURL: "Who", Company: "where", CustomFields: map[string]string{"industry": "Sector"}},
+			wantLeads: []models.Lead{{
+				PublicIdentifier: "amy",
+				ProfileURL:       "https://www.linkedin.com/in/amy/",
+				Company:          "Initech",
+				CustomFields:     map[string]string{"industry": "Software"},
+			}},
+			wantRows: 1,
+		},
+		{
+			name: "invalid rows and duplicates",
+			csv: "profile_url,first_name\n" +
+				"https://www.linkedin.com/in/amy/,Amy\n" +
+				"https://example.com/amy,Amy\n" +
+				",\n" +
+				"https://www.linkedin.com/in/AMY,Amy\n" +
+				",Ann\n",
+			wantLeads: []models.Lead{{
+				PublicIdentifier: "amy",
+				ProfileURL:       "https://www.linkedin.com/in/amy/",
+				FirstName:        "Amy",
+			}},
+			wantRows:       4,
+			wantDuplicates: 1,
+			wantErrorRows:  []int{3, 6},
+		},
+		{
+			name:    "no profile column",
+			csv:     "first_name\nJane\n",
+			wantErr: "no LinkedIn profile URL column",
+		},
+		{
+			name:    "mapped column missing",
+			csv:     "profile_url\nhttps://www.linkedin.com/in/amy/\n",
+			mapping: models.LeadColumnMapping{Company: "Employer"},
+			wantErr: `column "Employer" not found`,
+		},
+		{
+			name:    "empty file",
+			csv:     "",
+			wantErr: "the file is empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseLeadsCSV(strings.NewReader(tt.csv), tt.mapping)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for i := range result.Leads {
+				if len(result.Leads[i].CustomFields) == 0 {
+					result.Leads[i].CustomFields = nil
+				}
+			}
+			if !reflect.DeepEqual(result.Leads, tt.wantLeads) {
+				t.Errorf("leads = %+v, want %+v", result.Leads, tt.wantLeads)
+			}
+			if result.Rows != tt.wantRows {
+				t.Errorf("rows = %d, want %d", result.Rows, tt.wantRows)
+			}
+			if result.Duplicates != tt.wantDuplicates {
+				t.Errorf("duplicates = %d, want %d", result.Duplicates, tt.wantDuplicates)
+			}
+			var errorRows []int
+			for _, e := range result.Errors {
+				errorRows = append(errorRows, e.Row)
+			}
+			if !reflect.DeepEqual(errorRows, tt.wantErrorRows) {
+				t.Errorf("error rows = %v, want %v (%+v)", errorRows, tt.wantErrorRows, result.Errors)
+			}
+		})
+	}
+}
+
+func TestParseLeadsCSVTooManyRows(t *testing.T) {
+	csv := "profile_url\n" + strings.Repeat("https://www.linkedin.com/in/amy/\n", MaxLeadImportRows+1)
+	if _, err := ParseLeadsCSV(strings.NewReader(csv), models.LeadColumnMapping{}); !errors.Is(err, ErrTooManyRows) {
+		t.Fatalf("error = %v, want ErrTooManyRows", err)
+	}
+}
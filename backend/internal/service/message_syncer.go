@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	// syncPageSize is the page size used when polling chats and messages
+	syncPageSize = 50
+
+	// maxChatPages and maxMessagePages bound how many pages one sync run reads
+	// per pass. Where a pass stops short, its cursor is saved and the next run
+	// carries on from it, so the first sync of a large inbox is spread over
+	// several runs.
+	maxChatPages    = 4
+	maxMessagePages = 5
+
+	// maxBackfillChats is how many chats' older messages one run copies
+	maxBackfillChats = 10
+)
+
+// MessageSyncer periodically copies chats and messages from Unipile into the
+// local store. Each run first copies what's new: chats with activity since the
+// last run, and their messages back to the last run's newest one. It then
+// continues any backfill of older chats and messages left by earlier runs.
+type MessageSyncer struct {
+	unipile  UnipileClient
+	accounts *repository.LinkedAccountRepository
+	messages *repository.MessageRepository
+	interval time.Duration
+}
+
+// NewMessageSyncer creates a new message syncer
+func NewMessageSyncer(unipile UnipileClient, accounts *repository.LinkedAccountRepository, messages *repository.MessageRepository, interval time.Duration) *MessageSyncer {
+	return &MessageSyncer{
+		unipile:  unipile,
+		accounts: accounts,
+		messages: messages,
+		interval: interval,
+	}
+}
+
+// Start runs a sync immediately and then on every interval until ctx is done.
+// A non-positive interval disables the syncer.
+func (s *MessageSyncer) Start(ctx context.Context) {
+	if s.interval <= 0 {
+		slog.Info("Message syncer disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			s.SyncAll(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// SyncAll syncs the inbox of every healthy linked account
+func (s *MessageSyncer) SyncAll(ctx context.Context) {
+	accounts, err := s.accounts.FindAll()
+	if err != nil {
+		slog.ErrorContext(ctx, "Message sync failed to load accounts", "error", err)
+		return
+	}
+
+	for i := range accounts {
+		if ctx.Err() != nil {
+			return
+		}
+		if accounts[i].Status != models.AccountStatusOK {
+			continue
+		}
+		if err := s.SyncAccount(ctx, &accounts[i]); err != nil {
+			slog.ErrorContext(ctx, "Message sync failed", "account_id", accounts[i].ID, "error", err)
+		}
+	}
+}
+
+// SyncAccount syncs the chats with new activity of one linked account, then
+// continues copying its older chats and messages
+func (s *MessageSyncer) SyncAccount(ctx context.Context, account *models.LinkedAccount) error {
+	pending, err := s.messages.FindChatCursor(account.ID)
+	if err != nil {
+		return err
+	}
+
+	next, err := s.syncChats(ctx, account, "", true)
+	if err != nil {
+		return err
+	}
+	switch {
+	case next != "" && pending == "":
+		// The first sync, or a burst of activity, didn't fit in one run
+		if err := s.messages.SaveChatCursor(account.ID, next); err != nil {
+			return err
+		}
+	case pending != "":
+		if pending, err = s.syncChats(ctx, account, pending, false); err != nil {
+			return err
+		}
+		if err := s.messages.SaveChatCursor(account.ID, pending); err != nil {
+			return err
+		}
+	}
+
+	return s.backfillMessages(ctx, account)
+}
+
+// syncChats syncs up to maxChatPages pages of chats from cursor on, returning
+// the cursor to continue from, or "" once there is nothing left. Chats come
+// most recently active first, so with stopAtUnchanged the pass ends at the
+// first chat without activity since the last sync.
+func (s *MessageSyncer) syncChats(ctx context.Context, account *models.LinkedAccount, cursor string, stopAtUnchanged bool) (string, error) {
+	for page := 0; page < maxChatPages; page++ {
+		list, err := s.unipile.ListChats(ctx, ListChatsParams{
+			AccountID: account.AccountID,
+			Cursor:    cursor,
+			Limit:     syncPageSize,
+		})
+		if err != nil {
+			return cursor, err
+		}
+
+		for i := range list.Items {
+			changed, err := s.syncChat(ctx, account, &list.Items[i])
+			if err != nil {
+				return cursor, err
+			}
+			if !changed && stopAtUnchanged {
+				return "", nil
+			}
+		}
+
+		if list.Cursor == "" {
+			return "", nil
+		}
+		cursor = list.Cursor
+	}
+	return cursor, nil
+}
+
+// syncChat stores new messages of a chat, reporting whether the chat had
+// activity since the last sync. When the chat has more new messages than one
+// run reads, the rest are left to backfillMessages.
+func (s *MessageSyncer) syncChat(ctx context.Context, account *models.LinkedAccount, chat *Chat) (bool, error) {
+	lastActivity := parseTimestamp(chat.Timestamp)
+
+	var synced *time.Time
+	pending := ""
+	stored, err := s.messages.FindChat(account.ID, chat.ID)
+	switch {
+	case err == nil:
+		if stored.LastActivityAt != nil && lastActivity != nil && !lastActivity.After(*stored.LastActivityAt) {
+			return false, nil
+		}
+		synced = stored.LastActivityAt
+		pending = stored.BackfillCursor
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return true, err
+	}
+
+	// Messages stored by webhooks may sit above a gap, so new messages are
+	// read back to the newest one of the last sync, not to the first known one
+	next, err := s.syncMessages(ctx, account.ID, chat.ID, "", synced)
+	if err != nil {
+		return true, err
+	}
+	if pending == "" {
+		pending = next
+	}
+
+	now := time.Now()
+	dto := chat.ToModel(account.ID)
+	return true, s.messages.UpsertChat(&models.SyncedChat{
+		LinkedAccountID:    account.ID,
+		ChatID:             chat.ID,
+		ProviderID:         chat.ProviderID,
+		AttendeeProviderID: chat.AttendeeProviderID,
+		Name:               chat.Name,
+		Type:               dto.Type,
+		UnreadCount:        chat.UnreadCount,
+		LastActivityAt:     lastActivity,
+		LastSyncedAt:       &now,
+		BackfillCursor:     pending,
+	})
+}
+
+// backfillMessages continues copying the older messages of the account's
+// chats whose history isn't fully stored yet
+func (s *MessageSyncer) backfillMessages(ctx context.Context, account *models.LinkedAccount) error {
+	chats, err := s.messages.FindChatsToBackfill(account.ID, maxBackfillChats)
+	if err != nil {
+		return err
+	}
+
+	for i := range chats {
+		if ctx.Err() != nil {
+			return nil
+		}
+		next, err := s.syncMessages(ctx, account.ID, chats[i].ChatID, chats[i].BackfillCursor, nil)
+		if err != nil {
+			return err
+		}
+		if err := s.messages.UpdateBackfillCursor(chats[i].ID, next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncMessages stores up to maxMessagePages pages of a chat's messages from
+// cursor on, newest first, stopping at messages sent before until when it's
+// set. It returns the cursor to continue from, or "" once there is nothing
+// left.
+func (s *MessageSyncer) syncMessages(ctx context.Context, linkedAccountID uint, chatID, cursor string, until *time.Time) (string, error) {
+	for page := 0; page < maxMessagePages; page++ {
+		list, err := s.unipile.ListMessages(ctx, chatID, cursor, syncPageSize)
+		if err != nil {
+			return cursor, err
+		}
+
+		messages := make([]models.SyncedMessage, 0, len(list.Items))
+		reachedSynced := false
+		for i := range list.Items {
+			message := list.Items[i].ToSynced(linkedAccountID)
+			if until != nil && message.SentAt != nil && message.SentAt.Before(*until) {
+				reachedSynced = true
+				break
+			}
+			messages = append(messages, message)
+		}
+
+		if err := s.messages.UpsertMessages(messages); err != nil {
+			return cursor, err
+		}
+		if reachedSynced || list.Cursor == "" {
+			return "", nil
+		}
+		cursor = list.Cursor
+	}
+	return cursor, nil
+}
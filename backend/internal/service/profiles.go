@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ProfileService fetches LinkedIn profiles through Unipile and caches them
+// per linked account. Fetches count against the account's profile view
+// quota; cache hits don't, since LinkedIn never sees them.
+type ProfileService struct {
+	unipile UnipileClient
+	cache   *repository.ProfileRepository
+	quotas  *QuotaService
+	ttl     time.Duration
+}
+
+// NewProfileService creates a new profile service. A non-positive ttl
+// disables the cache.
+func NewProfileService(unipile UnipileClient, cache *repository.ProfileRepository, quotas *QuotaService, ttl time.Duration) *ProfileService {
+	return &ProfileService{
+		unipile: unipile,
+		cache:   cache,
+		quotas:  quotas,
+		ttl:     ttl,
+	}
+}
+
+// Get returns a profile by provider ID or public identifier as seen by the
+// account, from the cache when it holds a fresh copy and refresh is false.
+// hit reports whether the cache was used. Fetching fails with a
+// *QuotaExceededError when the account is out of profile views.
+func (s *ProfileService) Get(ctx context.Context, account *models.LinkedAccount, identifier string, refresh bool) (profile *models.CachedProfile, hit bool, err error) {
+	if s.ttl > 0 && !refresh {
+		cached, err := s.cache.WithContext(ctx).Find(account.ID, identifier)
+		switch {
+		case err == nil && time.Since(cached.FetchedAt) < s.ttl:
+			return cached, true, nil
+		case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+			slog.ErrorContext(ctx, "Failed to read profile cache", "account_id", account.ID, "error", err)
+		}
+	}
+
+	usage, err := s.quotas.Reserve(ctx, account.ID, models.ActionProfileView)
+	if err != nil {
+		return nil, false, err
+	}
+
+	fetched, err := s.unipile.GetProfile(ctx, account.AccountID, identifier)
+	if err != nil {
+		if releaseErr := s.quotas.Release(ctx, usage); releaseErr != nil {
+			slog.ErrorContext(ctx, "Failed to release quota usage", "usage_id", usage.ID, "error", releaseErr)
+		}
+		return nil, false, err
+	}
+
+	profile = &models.CachedProfile{
+		LinkedAccountID:  account.ID,
+		ProviderID:       fetched.ProviderID,
+		PublicIdentifier: fetched.PublicIdentifier,
+		Profile:          NewProfile(fetched),
+		FetchedAt:        time.Now(),
+	}
+	if s.ttl > 0 {
+		// A profile that can't be cached is still worth returning
+		if err := s.cache.WithContext(ctx).Save(profile); err != nil {
+			slog.ErrorContext(ctx, "Failed to cache profile", "provider_id", fetched.ProviderID, "account_id", account.ID, "error", err)
+		}
+	}
+	return profile, false, nil
+}
+
+// NewProfile normalizes a Unipile profile. Company and position come from the
+// current position when Unipile includes work experience, and otherwise from
+// a "Position at Company" headline.
+func NewProfile(u *UserProfile) models.Profile {
+	profile := models.Profile{
+		ProviderID:       u.ProviderID,
+		PublicIdentifier: u.PublicIdentifier,
+		FirstName:        u.FirstName,
+		LastName:         u.LastName,
+		FullName:         strings.TrimSpace(u.FirstName + " " + u.LastName),
+		Headline:         u.Headline,
+		Location:         u.Location,
+		Experience:       make([]models.ProfileExperience, 0, len(u.WorkExperience)),
+	}
+	profile.ProfileURL = profileURL(u.PublicIdentifier)
+
+	for _, experience := range u.WorkExperience {
+		profile.Experience = append(profile.Experience, models.ProfileExperience{
+			Company:   experience.Company,
+			Position:  experience.Position,
+			Location:  experience.Location,
+			StartDate: experience.Start,
+			EndDate:   experience.End,
+			Current:   experience.End == "",
+		})
+	}
+
+	if len(u.WorkExperience) > 0 {
+		profile.Company = u.WorkExperience[0].Company
+		profile.Position = u.WorkExperience[0].Position
+	} else if position, company, ok := strings.Cut(u.Headline, " at "); ok {
+		profile.Position = strings.TrimSpace(position)
+		profile.Company = strings.TrimSpace(company)
+	}
+	return profile
+}
+
+// profileURL returns the canonical URL of a public identifier's profile
+func profileURL(publicIdentifier string) string {
+	if publicIdentifier == "" {
+		return ""
+	}
+	return "https://www.linkedin.com/in/" + url.PathEscape(publicIdentifier) + "/"
+}
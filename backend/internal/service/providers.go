@@ -0,0 +1,153 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+)
+
+// Ways of authenticating an account when connecting it
+const (
+	AuthMethodCookie      = "cookie"      // a session cookie, such as LinkedIn's li_at
+	AuthMethodCredentials = "credentials" // username and password
+	AuthMethodQRCode      = "qr_code"     // a QR code scanned in the provider's mobile app
+	AuthMethodIMAP        = "imap"        // an email address and (app) password
+)
+
+// Provider describes a messaging provider accounts can be connected to
+// through Unipile
+type Provider struct {
+	Name        string   `json:"name"` // stored in LinkedAccount.Provider
+	DisplayName string   `json:"display_name"`
+	AuthMethods []string `json:"auth_methods"` // the first is the default
+	UnipileType string   `json:"-"`
+
+	mail *mailServers
+}
+
+// mailServers are the IMAP and SMTP servers of an email provider
+type mailServers struct {
+	imapHost string
+	imapPort int
+	smtpHost string
+	smtpPort int
+}
+
+// providers is the registry of connectable providers
+var providers = []Provider{
+	{
+		Name:        models.ProviderLinkedIn,
+		DisplayName: "LinkedIn",
+		AuthMethods: []string{AuthMethodCookie, AuthMethodCredentials},
+		UnipileType: "LINKEDIN",
+	},
+	{
+		Name:        models.ProviderWhatsApp,
+		DisplayName: "WhatsApp",
+		AuthMethods: []string{AuthMethodQRCode},
+		UnipileType: "WHATSAPP",
+	},
+	{
+		Name:        models.ProviderInstagram,
+		DisplayName: "Instagram",
+		AuthMethods: []string{AuthMethodCredentials},
+		UnipileType: "INSTAGRAM",
+	},
+	{
+		Name:        models.ProviderTelegram,
+		DisplayName: "Telegram",
+		AuthMethods: []string{AuthMethodQRCode},
+		UnipileType: "TELEGRAM",
+	},
+	{
+		Name:        models.ProviderGmail,
+		DisplayName: "Gmail",
+		AuthMethods: []string{AuthMethodIMAP},
+		UnipileType: "MAIL",
+		mail:        &mailServers{imapHost: "imap.gmail.com", imapPort: 993, smtpHost: "smtp.gmail.com", smtpPort: 465},
+	},
+	{
+		Name:        models.ProviderOutlook,
+		DisplayName: "Outlook",
+		AuthMethods: []string{AuthMethodIMAP},
+		UnipileType: "MAIL",
+		mail:        &mailServers{imapHost: "outlook.office365.com", imapPort: 993, smtpHost: "smtp.office365.com", smtpPort: 587},
+	},
+}
+
+// LinkedIn is the registry's LinkedIn entry, listed first
+var LinkedIn = &providers[0]
+
+// ErrUnknownProvider is returned for providers missing from the registry
+var ErrUnknownProvider = errors.New("unknown provider")
+
+// Providers lists the connectable providers
+func Providers() []Provider {
+	return providers
+}
+
+// FindProvider finds a provider by name, ignoring case
+func FindProvider(name string) (*Provider, error) {
+	for i := range providers {
+		if strings.EqualFold(providers[i].Name, name) {
+			return &providers[i], nil
+		}
+	}
+	return nil, ErrUnknownProvider
+}
+
+// NewConnectRequest validates a connection request against the provider's
+// auth methods and builds the Unipile request for it. Method defaults to the
+// provider's first auth method.
+func (p *Provider) NewConnectRequest(req models.ConnectAccountRequest) (ConnectRequest, error) {
+	method := req.Method
+	if method == "" {
+		method = p.AuthMethods[0]
+	}
+	if !p.supports(method) {
+		return ConnectRequest{}, fmt.Errorf("%s accounts can't be connected with %q, use one of: %s",
+			p.DisplayName, method, strings.Join(p.AuthMethods, ", "))
+	}
+
+	connect := ConnectRequest{Provider: p.UnipileType}
+	switch method {
+	case AuthMethodCookie:
+		if req.Cookie == "" {
+			return ConnectRequest{}, errors.New("cookie is required")
+		}
+		connect.AccessToken = req.Cookie
+
+	case AuthMethodCredentials:
+		if req.Username == "" || req.Password == "" {
+			return ConnectRequest{}, errors.New("username and password are required")
+		}
+		connect.Username, connect.Password = req.Username, req.Password
+
+	case AuthMethodQRCode:
+		// Unipile answers with a QR code checkpoint
+
+	case AuthMethodIMAP:
+		if req.Email == "" || req.Password == "" {
+			return ConnectRequest{}, errors.New("email and password are required")
+		}
+		if !strings.Contains(req.Email, "@") {
+			return ConnectRequest{}, errors.New("email is not an email address")
+		}
+		connect.IMAPUser, connect.IMAPPassword = req.Email, req.Password
+		connect.IMAPHost, connect.IMAPPort = p.mail.imapHost, p.mail.imapPort
+		connect.SMTPUser, connect.SMTPPassword = req.Email, req.Password
+		connect.SMTPHost, connect.SMTPPort = p.mail.smtpHost, p.mail.smtpPort
+	}
+	return connect, nil
+}
+
+func (p *Provider) supports(method string) bool {
+	for _, supported := range p.AuthMethods {
+		if supported == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/config"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+)
+
+// Rolling windows quotas are counted over
+const (
+	quotaDay  = 24 * time.Hour
+	quotaWeek = 7 * quotaDay
+)
+
+// QuotaActions lists the actions limited by quotas, in display order
+var QuotaActions = []string{models.ActionInvite, models.ActionMessage, models.ActionProfileView}
+
+// QuotaExceededError is returned when an action would exceed a quota of the
+// linked account
+type QuotaExceededError struct {
+	Action  string
+	Window  string // "daily" or "weekly"
+	Limit   int
+	Used    int
+	ResetAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s %s quota of %d reached, resets at %s",
+		e.Window, e.Action, e.Limit, e.ResetAt.UTC().Format(time.RFC3339))
+}
+
+// QuotaService enforces per linked account limits on outbound actions. Each
+// action is reserved before the Unipile call that performs it, so concurrent
+// requests can't overshoot a limit.
+type QuotaService struct {
+	usages *repository.QuotaRepository
+	limits map[string]config.QuotaLimits
+
+	// mu makes counting and recording a usage atomic
+	mu sync.Mutex
+}
+
+// NewQuotaService creates a new quota service
+func NewQuotaService(usages *repository.QuotaRepository, cfg config.QuotaConfig) *QuotaService {
+	return &QuotaService{
+		usages: usages,
+		limits: map[string]config.QuotaLimits{
+			models.ActionInvite:      cfg.Invite,
+			models.ActionMessage:     cfg.Message,
+			models.ActionProfileView: cfg.ProfileView,
+		},
+	}
+}
+
+// Reserve counts one action against the quotas of a linked account. It
+// returns a *QuotaExceededError if a daily or weekly limit is already reached.
+// Callers should Release the usage if the action then fails.
+func (s *QuotaService) Reserve(ctx context.Context, linkedAccountID uint, action string) (*models.QuotaUsage, error) {
+	usages := s.usages.WithContext(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if err := usages.DeleteBefore(now.Add(-quotaWeek)); err != nil {
+		return nil, err
+	}
+	if err := s.check(usages, linkedAccountID, action, now); err != nil {
+		return nil, err
+	}
+
+	usage := &models.QuotaUsage{LinkedAccountID: linkedAccountID, Action: action, CreatedAt: now}
+	if err := usages.Create(usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// Check is Reserve without counting the action: it returns a
+// *QuotaExceededError if a limit is already reached. Actions queued for later
+// can check that they aren't doomed to wait for the quota to reset.
+func (s *QuotaService) Check(ctx context.Context, linkedAccountID uint, action string) error {
+	return s.check(s.usages.WithContext(ctx), linkedAccountID, action, time.Now())
+}
+
+// check returns a *QuotaExceededError if a daily or weekly limit of the
+// action is reached at now
+func (s *QuotaService) check(usages *repository.QuotaRepository, linkedAccountID uint, action string, now time.Time) error {
+	limits := s.limits[action]
+	for _, w := range []struct {
+		name   string
+		length time.Duration
+		limit  int
+	}{
+		{"daily", quotaDay, limits.Daily},
+		{"weekly", quotaWeek, limits.Weekly},
+	} {
+		if w.limit <= 0 {
+			continue
+		}
+
+		since := now.Add(-w.length)
+		used, err := usages.CountSince(linkedAccountID, action, since)
+		if err != nil {
+			return err
+		}
+		if used < w.limit {
+			continue
+		}
+
+		// A slot frees up once enough of the oldest actions leave the window
+		oldest, err := usages.NthOldestSince(linkedAccountID, action, since, used-w.limit+1)
+		if err != nil {
+			return err
+		}
+		return &QuotaExceededError{
+			Action:  action,
+			Window:  w.name,
+			Limit:   w.limit,
+			Used:    used,
+			ResetAt: oldest.Add(w.length),
+		}
+	}
+	return nil
+}
+
+// Release gives back an action reserved for a call that failed
+func (s *QuotaService) Release(ctx context.Context, usage *models.QuotaUsage) error {
+	return s.usages.WithContext(ctx).Delete(usage)
+}
+
+// Usage reports the current usage of every quota of a linked account
+func (s *QuotaService) Usage(ctx context.Context, linkedAccountID uint) ([]models.ActionQuota, error) {
+	usages := s.usages.WithContext(ctx)
+	now := time.Now()
+	quotas := make([]models.ActionQuota, 0, len(QuotaActions))
+	for _, action := range QuotaActions {
+		limits := s.limits[action]
+		daily, err := window(usages, linkedAccountID, action, now.Add(-quotaDay), quotaDay, limits.Daily)
+		if err != nil {
+			return nil, err
+		}
+		weekly, err := window(usages, linkedAccountID, action, now.Add(-quotaWeek), quotaWeek, limits.Weekly)
+		if err != nil {
+			return nil, err
+		}
+		quotas = append(quotas, models.ActionQuota{Action: action, Daily: daily, Weekly: weekly})
+	}
+	return quotas, nil
+}
+
+// window reports the usage of one action within one window
+func window(usages *repository.QuotaRepository, linkedAccountID uint, action string, since time.Time, length time.Duration, limit int) (models.QuotaWindow, error) {
+	used, err := usages.CountSince(linkedAccountID, action, since)
+	if err != nil {
+		return models.QuotaWindow{}, err
+	}
+
+	window := models.QuotaWindow{Limit: limit, Used: used}
+	if limit > 0 {
+		remaining := limit - used
+		if remaining < 0 {
+			remaining = 0
+		}
+		window.Remaining = &remaining
+	}
+	if used > 0 {
+		oldest, err := usages.NthOldestSince(linkedAccountID, action, since, 1)
+		if err != nil {
+			return models.QuotaWindow{}, err
+		}
+		resetAt := oldest.Add(length)
+		window.ResetAt = &resetAt
+	}
+	return window, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/config"
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+)
+
+func TestQuotaServiceReserve(t *testing.T) {
+	cfg := config.QuotaConfig{
+		Invite:  config.QuotaLimits{Daily: 2, Weekly: 3},
+		Message: config.QuotaLimits{Daily: 0, Weekly: 0},
+	}
+
+	tests := []struct {
+		name   string
+		action string
+		// used are the ages of the account's earlier usages of action
+		used       []time.Duration
+		wantWindow string // empty if the action is allowed
+		// wantResetIn is how long after now the quota resets
+		wantResetIn time.Duration
+	}{
+		{name: "under the limits", action: models.ActionInvite, used: []time.Duration{time.Hour}},
+		{
+			name:        "daily limit reached",
+			action:      models.ActionInvite,
+			used:        []time.Duration{3 * time.Hour, time.Hour},
+			wantWindow:  "daily",
+			wantResetIn: 21 * time.Hour,
+		},
+		{
+			name:        "weekly limit reached",
+			action:      models.ActionInvite,
+			used:        []time.Duration{6 * quotaDay, 3 * quotaDay, time.Hour},
+			wantWindow:  "weekly",
+			wantResetIn: quotaDay,
+		},
+		{name: "usages older than a week", action: models.ActionInvite, used: []time.Duration{8 * quotaDay, 8 * quotaDay, 8 * quotaDay}},
+		{name: "no limits", action: models.ActionMessage, used: []time.Duration{time.Hour, time.Hour, time.Hour}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := newTestDB(t, &models.QuotaUsage{})
+			usages := repository.NewQuotaRepository(db)
+			s := NewQuotaService(usages, cfg)
+
+			now := time.Now()
+			for _, age := range tt.used {
+				if err := usages.Create(&models.QuotaUsage{LinkedAccountID: 1, Action: tt.action, CreatedAt: now.Add(-age)}); err != nil {
+					t.Fatalf("create usage: %v", err)
+				}
+			}
+
+			checkErr := s.Check(ctx, 1, tt.action)
+			usage, err := s.Reserve(ctx, 1, tt.action)
+			if fmt.Sprint(checkErr) != fmt.Sprint(err) {
+				t.Errorf("Check error = %v, Reserve error = %v", checkErr, err)
+			}
+			if tt.wantWindow == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if err := s.Release(ctx, usage); err != nil {
+					t.Fatalf("release: %v", err)
+				}
+				return
+			}
+
+			var exceeded *QuotaExceededError
+			if !errors.As(err, &exceeded) {
+				t.Fatalf("error = %v, want *QuotaExceededError", err)
+			}
+			if exceeded.Window != tt.wantWindow {
+				t.Errorf("window = %q, want %q", exceeded.Window, tt.wantWindow)
+			}
+			if resetIn := time.Until(exceeded.ResetAt); resetIn > tt.wantResetIn || resetIn < tt.wantResetIn-time.Minute {
+				t.Errorf("resets in %v, want %v", resetIn, tt.wantResetIn)
+			}
+
+			// Refused actions don't count
+			if used, err := usages.CountSince(1, tt.action, now.Add(-quotaWeek)); err != nil || used != len(tt.used) {
+				t.Errorf("%d usages (error %v), want %d", used, err, len(tt.used))
+			}
+			// Other accounts have quotas of their own
+			if _, err := s.Reserve(ctx, 2, tt.action); err != nil {
+				t.Errorf("reserve for another account: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+)
+
+// SampleTemplateData is the profile templates are previewed against by default
+var SampleTemplateData = models.TemplateData{
+	FirstName:        "Jane",
+	LastName:         "Doe",
+	FullName:         "Jane Doe",
+	Headline:         "VP of Sales at Acme Corp",
+	Company:          "Acme Corp",
+	Position:         "VP of Sales",
+	Location:         "San Francisco Bay Area",
+	PublicIdentifier: "jane-doe",
+}
+
+const (
+	// customPrefix prefixes the variable names of custom fields
+	customPrefix = "Custom."
+
+	// maxRenderedLength bounds rendered messages, in bytes, matching the
+	// longest message accepted by the API
+	maxRenderedLength = 8000
+)
+
+// errRenderedTooLong stops templates rendering past maxRenderedLength
+var errRenderedTooLong = fmt.Errorf("rendered message longer than %d bytes", maxRenderedLength)
+
+// templateFuncs are the builtin functions templates may call. The others
+// either can produce unbounded output, like printf with a huge width, or
+// have no use in a message.
+var templateFuncs = map[string]bool{
+	"and": true, "or": true, "not": true, "len": true, "index": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+}
+
+// MessageTemplate is a parsed message template
+type MessageTemplate struct {
+	tmpl *template.Template
+
+	// Variables lists every placeholder used; Required those used outside
+	// {{if}}, {{with}} and {{range}} blocks, which must not render empty
+	Variables []string
+	Required  []string
+}
+
+// ParseMessageTemplate parses a template body and checks that it only uses
+// known variables, and only the actions and functions whose output and run
+// time are bounded by the recipient's data
+func ParseMessageTemplate(body string) (*MessageTemplate, error) {
+	tmpl, err := template.New("message").Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	if len(tmpl.Templates()) > 1 {
+		return nil, errors.New("invalid template: {{define}} and {{block}} are not supported")
+	}
+	if err := checkNode(tmpl.Tree.Root, false); err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	t := &MessageTemplate{tmpl: tmpl}
+	variables, required := map[string]bool{}, map[string]bool{}
+	collectFields(tmpl.Tree.Root, false, variables, required)
+	t.Variables, t.Required = sortedKeys(variables), sortedKeys(required)
+
+	var unknown []string
+	for _, name := range t.Variables {
+		if strings.HasPrefix(name, customPrefix) {
+			continue
+		}
+		if _, ok := reflect.TypeOf(models.TemplateData{}).FieldByName(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown template variables: %s", strings.Join(unknown, ", "))
+	}
+
+	// Anything else invalid, such as fields of fields, surfaces when executing
+	if _, err := t.execute(SampleTemplateData); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Render fills the template for one recipient. It fails with a
+// *MissingVariablesError if a required variable is empty for them.
+func (t *MessageTemplate) Render(data models.TemplateData) (string, error) {
+	values := reflect.ValueOf(data)
+	var missing []string
+	for _, name := range t.Required {
+		if key, ok := strings.CutPrefix(name, customPrefix); ok {
+			if data.Custom[key] == "" {
+				missing = append(missing, name)
+			}
+			continue
+		}
+		if field := values.FieldByName(name); !field.IsValid() || field.Kind() != reflect.String || field.String() == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", &MissingVariablesError{Variables: missing}
+	}
+
+	return t.execute(data)
+}
+
+// RenderPartial fills the template without checking required variables,
+// leaving missing ones empty
+func (t *MessageTemplate) RenderPartial(data models.TemplateData) (string, error) {
+	return t.execute(data)
+}
+
+func (t *MessageTemplate) execute(data models.TemplateData) (string, error) {
+	// Custom fields are free-form, so absent ones render empty rather than
+	// failing like unknown variables
+	custom := make(map[string]string, len(data.Custom))
+	for key, value := range data.Custom {
+		custom[key] = value
+	}
+	for _, name := range t.Variables {
+		if key, ok := strings.CutPrefix(name, customPrefix); ok {
+			custom[key] = data.Custom[key]
+		}
+	}
+	data.Custom = custom
+
+	out := &limitedWriter{limit: maxRenderedLength}
+	if err := t.tmpl.Execute(out, data); err != nil {
+		if errors.Is(err, errRenderedTooLong) {
+			return "", errRenderedTooLong
+		}
+		return "", fmt.Errorf("invalid template: %w", err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// limitedWriter collects output, failing writes past limit bytes
+type limitedWriter struct {
+	strings.Builder
+	limit int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.Len()+len(p) > w.limit {
+		return 0, errRenderedTooLong
+	}
+	return w.Builder.Write(p)
+}
+
+// MissingVariablesError reports template variables the recipient's profile
+// has no value for
+type MissingVariablesError struct {
+	Variables []string
+}
+
+func (e *MissingVariablesError) Error() string {
+	return "missing template variables: " + strings.Join(e.Variables, ", ")
+}
+
+// NewTemplateData builds template variables from a LinkedIn profile
+func NewTemplateData(profile *models.Profile) models.TemplateData {
+	return models.TemplateData{
+		FirstName:        profile.FirstName,
+		LastName:         profile.LastName,
+		FullName:         profile.FullName,
+		Headline:         profile.Headline,
+		Company:          profile.Company,
+		Position:         profile.Position,
+		Location:         profile.Location,
+		PublicIdentifier: profile.PublicIdentifier,
+	}
+}
+
+// checkNode rejects the parts of a template that could run unbounded: calls
+// to functions other than templateFuncs, {{template}}, and {{range}} over
+// anything but a variable or nested in another {{range}}
+func checkNode(node parse.Node, inRange bool) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := checkNode(child, inRange); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		return checkPipe(n.Pipe)
+	case *parse.IfNode:
+		return checkBranch(&n.BranchNode, inRange)
+	case *parse.WithNode:
+		return checkBranch(&n.BranchNode, inRange)
+	case *parse.RangeNode:
+		if inRange {
+			return errors.New("nested {{range}} is not supported")
+		}
+		if !rangesOverVariable(n.Pipe) {
+			return errors.New("{{range}} only works over a variable, such as .Custom")
+		}
+		return checkBranch(&n.BranchNode, true)
+	case *parse.TemplateNode:
+		return errors.New("{{template}} is not supported")
+	}
+	return nil
+}
+
+func checkBranch(n *parse.BranchNode, inRange bool) error {
+	if err := checkPipe(n.Pipe); err != nil {
+		return err
+	}
+	if err := checkNode(n.List, inRange); err != nil {
+		return err
+	}
+	return checkNode(n.ElseList, inRange)
+}
+
+func checkPipe(pipe *parse.PipeNode) error {
+	if pipe == nil {
+		return nil
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.IdentifierNode:
+				if !templateFuncs[a.Ident] {
+					return fmt.Errorf("function %q is not supported", a.Ident)
+				}
+			case *parse.PipeNode:
+				if err := checkPipe(a); err != nil {
+					return err
+				}
+			case *parse.ChainNode:
+				if p, ok := a.Node.(*parse.PipeNode); ok {
+					if err := checkPipe(p); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// rangesOverVariable reports whether a {{range}} pipeline is a lone field or
+// variable, such as .Custom or $.Custom
+func rangesOverVariable(pipe *parse.PipeNode) bool {
+	if len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+	switch arg := pipe.Cmds[0].Args[0].(type) {
+	case *parse.FieldNode:
+		return true
+	case *parse.VariableNode:
+		return len(arg.Ident) > 1
+	}
+	return false
+}
+
+// collectFields records the fields a template node uses. Fields inside
+// conditional blocks are optional.
+func collectFields(node parse.Node, optional bool, variables, required map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectFields(child, optional, variables, required)
+		}
+	case *parse.ActionNode:
+		collectPipe(n.Pipe, optional, variables, required)
+	case *parse.IfNode:
+		collectBranch(&n.BranchNode, variables, required)
+	case *parse.WithNode:
+		collectBranch(&n.BranchNode, variables, required)
+	case *parse.RangeNode:
+		collectBranch(&n.BranchNode, variables, required)
+	}
+}
+
+func collectBranch(n *parse.BranchNode, variables, required map[string]bool) {
+	collectPipe(n.Pipe, true, variables, required)
+	collectFields(n.List, true, variables, required)
+	collectFields(n.ElseList, true, variables, required)
+}
+
+func collectPipe(pipe *parse.PipeNode, optional bool, variables, required map[string]bool) {
+	if pipe == nil {
+		return
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			field, ok := arg.(*parse.FieldNode)
+			if !ok || len(field.Ident) == 0 {
+				continue
+			}
+			name := field.Ident[0]
+			if name == "Custom" && len(field.Ident) > 1 {
+				name = customPrefix + field.Ident[1]
+			}
+			variables[name] = true
+			if !optional {
+				required[name] = true
+			}
+		}
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
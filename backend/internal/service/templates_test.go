@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+)
+
+func TestParseMessageTemplate(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantRequired []string
+		wantErr      string
+	}{
+		{
+			name:         "variables outside conditionals are required",
+			body:         "Hi {{.FirstName}}{{if .Company}} at {{.Company}}{{end}}",
+			wantRequired: []string{"FirstName"},
+		},
+		{
+			name:         "custom fields",
+			body:         "{{.Custom.industry}} {{with .Custom.city}}in {{.}}{{end}}",
+			wantRequired: []string{"Custom.industry"},
+		},
+		{
+			name:         "comparisons and range over custom fields",
+			body:         `{{if eq .Company "Acme"}}Hi{{end}}{{range $k, $v := .Custom}} {{$k}}={{$v}}{{end}} {{len .FirstName}}`,
+			wantRequired: []string{"FirstName"},
+		},
+		{name: "syntax error", body: "Hi {{.FirstName", wantErr: "invalid template"},
+		{name: "unknown variable", body: "Hi {{.Nickname}}", wantErr: "unknown template variables: Nickname"},
+		{name: "printf", body: `{{printf "%0999999999d" 1}}`, wantErr: `function "printf" is not supported`},
+		{name: "printf in a subexpression", body: `{{len (printf "%d" 1)}}`, wantErr: `function "printf" is not supported`},
+		{name: "range over a number", body: "{{range 1000000000}}x{{end}}", wantErr: "{{range}} only works over a variable"},
+		{name: "nested range", body: "{{range .Custom}}{{range $.Custom}}x{{end}}{{end}}", wantErr: "nested {{range}}"},
+		{name: "recursive template", body: `{{define "a"}}{{template "a"}}{{end}}`, wantErr: "{{define}}"},
+		{name: "too long", body: strings.Repeat("{{.FullName}}", 1001), wantErr: "longer than 8000 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseMessageTemplate(tt.body)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(tmpl.Required) == 0 {
+				tmpl.Required = nil
+			}
+			if !reflect.DeepEqual(tmpl.Required, tt.wantRequired) {
+				t.Errorf("required = %v, want %v", tmpl.Required, tt.wantRequired)
+			}
+		})
+	}
+}
+
+func TestMessageTemplateRender(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		data        models.TemplateData
+		want        string
+		wantMissing []string
+		wantErr     error
+	}{
+		{
+			name: "all variables present",
+			body: "Hi {{.FirstName}}, how are things at {{.Company}}?",
+			data: models.TemplateData{FirstName: "Jane", Company: "Acme"},
+			want: "Hi Jane, how are things at Acme?",
+		},
+		{
+			name: "optional variable missing",
+			body: "Hi {{.FirstName}}{{if .Company}} at {{.Company}}{{end}}!",
+			data: models.TemplateData{FirstName: "Jane"},
+			want: "Hi Jane!",
+		},
+		{
+			name:        "required variables missing",
+			body:        "Hi {{.FirstName}} from {{.Company}}, {{.Custom.team}}",
+			data:        models.TemplateData{FirstName: "Jane"},
+			wantMissing: []string{"Company", "Custom.team"},
+		},
+		{
+			name: "custom field",
+			body: "Loved your talk on {{.Custom.topic}}",
+			data: models.TemplateData{Custom: map[string]string{"topic": "pricing"}},
+			want: "Loved your talk on pricing",
+		},
+		{
+			name: "absent optional custom field renders empty",
+			body: "Hi{{with .Custom.nickname}} {{.}}{{end}}",
+			data: models.TemplateData{},
+			want: "Hi",
+		},
+		{
+			name:    "output too long",
+			body:    "{{.Headline}}{{.Headline}}",
+			data:    models.TemplateData{Headline: strings.Repeat("x", 5000)},
+			wantErr: errRenderedTooLong,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := ParseMessageTemplate(tt.body)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+
+			got, err := tmpl.Render(tt.data)
+			var missing *MissingVariablesError
+			switch {
+			case tt.wantMissing != nil:
+				if !errors.As(err, &missing) || !reflect.DeepEqual(missing.Variables, tt.wantMissing) {
+					t.Fatalf("error = %v, want missing %v", err, tt.wantMissing)
+				}
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case got != tt.want:
+				t.Errorf("rendered %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
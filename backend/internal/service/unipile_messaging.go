@@ -0,0 +1,251 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+)
+
+// ListChatsParams filters and paginates a chat listing
+type ListChatsParams struct {
+	AccountID string
+	Cursor    string
+	Limit     int
+	Unread    bool
+}
+
+// ChatList is a page of chats as returned by Unipile's GET /chats
+type ChatList struct {
+	Object string `json:"object"`
+	Items  []Chat `json:"items"`
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// Chat is a conversation as returned by Unipile
+type Chat struct {
+	Object             string `json:"object"`
+	ID                 string `json:"id"`
+	AccountID          string `json:"account_id"`
+	AccountType        string `json:"account_type"`
+	ProviderID         string `json:"provider_id"`
+	AttendeeProviderID string `json:"attendee_provider_id,omitempty"`
+	Name               string `json:"name,omitempty"`
+	Type               int    `json:"type"` // 0 = direct, 1 = group, 2 = channel
+	Timestamp          string `json:"timestamp,omitempty"`
+	UnreadCount        int    `json:"unread_count"`
+	Archived           int    `json:"archived"`
+	Muted              int    `json:"muted"`
+	ReadOnly           int    `json:"read_only"`
+	Subject            string `json:"subject,omitempty"`
+}
+
+// MessageList is a page of messages as returned by Unipile
+type MessageList struct {
+	Object string    `json:"object"`
+	Items  []Message `json:"items"`
+	Cursor string    `json:"cursor,omitempty"`
+}
+
+// Message is a chat message as returned by Unipile
+type Message struct {
+	Object      string       `json:"object"`
+	ID          string       `json:"id"`
+	AccountID   string       `json:"account_id"`
+	ChatID      string       `json:"chat_id"`
+	ProviderID  string       `json:"provider_id"`
+	SenderID    string       `json:"sender_id"`
+	Text        string       `json:"text"`
+	Timestamp   string       `json:"timestamp"`
+	IsSender    int          `json:"is_sender"`
+	Seen        int          `json:"seen"`
+	Deleted     int          `json:"deleted"`
+	Edited      int          `json:"edited"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+// Attachment is the metadata of a file attached to a message
+type Attachment struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"` // img, video, audio, file, linkedin_post, ...
+	FileName    string `json:"file_name,omitempty"`
+	FileSize    int64  `json:"file_size,omitempty"`
+	MimeType    string `json:"mimetype,omitempty"`
+	URL         string `json:"url,omitempty"`
+	Unavailable bool   `json:"unavailable"`
+}
+
+// MessageSent is Unipile's response to sending a message in a chat
+type MessageSent struct {
+	Object    string `json:"object"`
+	MessageID string `json:"message_id"`
+}
+
+// ChatStarted is Unipile's response to starting a new chat
+type ChatStarted struct {
+	Object    string `json:"object"`
+	ChatID    string `json:"chat_id"`
+	MessageID string `json:"message_id"`
+}
+
+// ListChats lists the chats of an account, newest first
+func (s *UnipileService) ListChats(ctx context.Context, params ListChatsParams) (*ChatList, error) {
+	query := url.Values{}
+	query.Set("account_id", params.AccountID)
+	if params.Cursor != "" {
+		query.Set("cursor", params.Cursor)
+	}
+	if params.Limit > 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.Unread {
+		query.Set("unread", "true")
+	}
+
+	var list ChatList
+	if _, err := s.do(ctx, http.MethodGet, "/chats?"+query.Encode(), nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetChat retrieves a single chat
+func (s *UnipileService) GetChat(ctx context.Context, chatID string) (*Chat, error) {
+	var chat Chat
+	if _, err := s.do(ctx, http.MethodGet, "/chats/"+url.PathEscape(chatID), nil, &chat); err != nil {
+		return nil, err
+	}
+	return &chat, nil
+}
+
+// ListMessages lists the messages of a chat, newest first
+func (s *UnipileService) ListMessages(ctx context.Context, chatID, cursor string, limit int) (*MessageList, error) {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var list MessageList
+	path := "/chats/" + url.PathEscape(chatID) + "/messages?" + query.Encode()
+	if _, err := s.do(ctx, http.MethodGet, path, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// SendMessage sends a text message in an existing chat
+func (s *UnipileService) SendMessage(ctx context.Context, chatID, text string) (*MessageSent, error) {
+	var sent MessageSent
+	path := "/chats/" + url.PathEscape(chatID) + "/messages"
+	if _, err := s.doForm(ctx, http.MethodPost, path, url.Values{"text": {text}}, &sent); err != nil {
+		return nil, err
+	}
+	return &sent, nil
+}
+
+// StartChat starts a new chat with an attendee, identified by their LinkedIn
+// provider ID, and sends the first message
+func (s *UnipileService) StartChat(ctx context.Context, accountID, attendeeProviderID, text string) (*ChatStarted, error) {
+	fields := url.Values{
+		"account_id":    {accountID},
+		"attendees_ids": {attendeeProviderID},
+		"text":          {text},
+	}
+
+	var started ChatStarted
+	if _, err := s.doForm(ctx, http.MethodPost, "/chats", fields, &started); err != nil {
+		return nil, err
+	}
+	return &started, nil
+}
+
+// ToModel normalizes a Unipile chat into the API's chat DTO
+func (c *Chat) ToModel(linkedAccountID uint) models.Chat {
+	chatType := models.ChatTypeDirect
+	switch c.Type {
+	case 1:
+		chatType = models.ChatTypeGroup
+	case 2:
+		chatType = models.ChatTypeChannel
+	}
+
+	return models.Chat{
+		ID:                 c.ID,
+		AccountID:          linkedAccountID,
+		ProviderID:         c.ProviderID,
+		AttendeeProviderID: c.AttendeeProviderID,
+		Name:               c.Name,
+		Subject:            c.Subject,
+		Type:               chatType,
+		UnreadCount:        c.UnreadCount,
+		Archived:           c.Archived != 0,
+		Muted:              c.Muted != 0,
+		ReadOnly:           c.ReadOnly != 0,
+		LastActivityAt:     parseTimestamp(c.Timestamp),
+	}
+}
+
+// ToModel normalizes a Unipile message into the API's message DTO
+func (m *Message) ToModel(linkedAccountID uint) models.Message {
+	attachments := make([]models.Attachment, 0, len(m.Attachments))
+	for _, a := range m.Attachments {
+		attachments = append(attachments, models.Attachment{
+			ID:          a.ID,
+			Type:        a.Type,
+			FileName:    a.FileName,
+			FileSize:    a.FileSize,
+			MimeType:    a.MimeType,
+			Unavailable: a.Unavailable,
+		})
+	}
+
+	return models.Message{
+		ID:         m.ID,
+		ChatID:     m.ChatID,
+		AccountID:  linkedAccountID,
+		ProviderID: m.ProviderID,
+		Sender: models.MessageSender{
+			ID:   m.SenderID,
+			IsMe: m.IsSender != 0,
+		},
+		Text:        m.Text,
+		SentAt:      parseTimestamp(m.Timestamp),
+		Seen:        m.Seen != 0,
+		Edited:      m.Edited != 0,
+		Deleted:     m.Deleted != 0,
+		Attachments: attachments,
+	}
+}
+
+// ToSynced converts a Unipile message into a row of the local message store
+func (m *Message) ToSynced(linkedAccountID uint) models.SyncedMessage {
+	return models.SyncedMessage{
+		LinkedAccountID: linkedAccountID,
+		MessageID:       m.ID,
+		ChatID:          m.ChatID,
+		ProviderID:      m.ProviderID,
+		SenderID:        m.SenderID,
+		IsMe:            m.IsSender != 0,
+		Text:            m.Text,
+		SentAt:          parseTimestamp(m.Timestamp),
+	}
+}
+
+// parseTimestamp parses an ISO 8601 timestamp from Unipile, returning nil
+// when it is missing or malformed
+func parseTimestamp(value string) *time.Time {
+	if value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -2,153 +2,673 @@ package service
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"math/rand"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/johnson7543/chatsheet-assessment/internal/config"
+	"github.com/johnson7543/chatsheet-assessment/internal/logging"
 )
 
+const (
+	defaultUnipileTimeout    = 30 * time.Second
+	defaultUnipileRetryDelay = 2 * time.Second
+	maxUnipileRetryDelay     = 30 * time.Second
+)
+
+// UnipileClient is the set of Unipile API operations used by the handlers.
+// Handlers depend on this interface so a fake can be injected in tests.
+type UnipileClient interface {
+	ConnectWithCookie(ctx context.Context, cookie string) (*ConnectResponse, error)
+	ConnectWithCredentials(ctx context.Context, username, password string) (*ConnectResponse, error)
+	ReconnectWithCookie(ctx context.Context, accountID, cookie string) (*ConnectResponse, error)
+	ReconnectWithCredentials(ctx context.Context, accountID, username, password string) (*ConnectResponse, error)
+	ConnectAccount(ctx context.Context, req ConnectRequest) (*ConnectResponse, error)
+	CreateHostedAuthLink(ctx context.Context, req HostedAuthLinkRequest) (*HostedAuthLink, error)
+	SolveCheckpoint(ctx context.Context, provider, accountID, code string) (*ConnectResponse, error)
+	GetAccount(ctx context.Context, accountID string) (*Account, error)
+	DeleteAccount(ctx context.Context, accountID string) error
+	ListChats(ctx context.Context, params ListChatsParams) (*ChatList, error)
+	GetChat(ctx context.Context, chatID string) (*Chat, error)
+	ListMessages(ctx context.Context, chatID, cursor string, limit int) (*MessageList, error)
+	SendMessage(ctx context.Context, chatID, text string) (*MessageSent, error)
+	StartChat(ctx context.Context, accountID, attendeeProviderID, text string) (*ChatStarted, error)
+	GetProfile(ctx context.Context, accountID, identifier string) (*UserProfile, error)
+	GetOwnProfile(ctx context.Context, accountID string) (*OwnProfile, error)
+	SendInvitation(ctx context.Context, accountID, providerID, note string) (*InvitationSent, error)
+	CancelInvitation(ctx context.Context, accountID, invitationID string) error
+	ListRelations(ctx context.Context, accountID, cursor string, limit int) (*RelationList, error)
+}
+
 // UnipileService handles interactions with the Unipile API
 type UnipileService struct {
-	apiKey string
-	apiURL string
-	client *http.Client
+	apiKey        string
+	apiURL        string
+	client        *http.Client
+	timeout       time.Duration
+	retryAttempts int
+	retryDelay    time.Duration
 }
 
+// Ensure UnipileService satisfies UnipileClient
+var _ UnipileClient = (*UnipileService)(nil)
+
 // NewUnipileService creates a new Unipile service
 func NewUnipileService() *UnipileService {
+	cfg := config.App.Unipile
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultUnipileTimeout
+	}
+	retryDelay := cfg.RetryDelay
+	if retryDelay <= 0 {
+		retryDelay = defaultUnipileRetryDelay
+	}
+	retryAttempts := cfg.RetryAttempts
+	if retryAttempts < 0 {
+		retryAttempts = 0
+	}
+
 	return &UnipileService{
-		apiKey: config.App.UnipileAPIKey,
-		apiURL: config.App.Unipile.APIURL,
-		client: &http.Client{},
+		apiKey:        config.App.UnipileAPIKey,
+		apiURL:        cfg.APIURL,
+		client:        &http.Client{},
+		timeout:       timeout,
+		retryAttempts: retryAttempts,
+		retryDelay:    retryDelay,
 	}
 }
 
 // ConnectRequest represents a request to connect an account
 type ConnectRequest struct {
-	Provider string                 `json:"provider"`
-	Cookie   string                 `json:"cookie,omitempty"`
-	Username string                 `json:"username,omitempty"`
-	Password string                 `json:"password,omitempty"`
-	Type     string                 `json:"type,omitempty"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Provider    string `json:"provider"`
+	AccessToken string `json:"access_token,omitempty"` // For cookie auth (li_at cookie)
+	Username    string `json:"username,omitempty"`     // For credentials auth
+	Password    string `json:"password,omitempty"`     // For credentials auth
+
+	// For email accounts connected over IMAP and SMTP
+	IMAPUser     string `json:"imap_user,omitempty"`
+	IMAPPassword string `json:"imap_password,omitempty"`
+	IMAPHost     string `json:"imap_host,omitempty"`
+	IMAPPort     int    `json:"imap_port,omitempty"`
+	SMTPUser     string `json:"smtp_user,omitempty"`
+	SMTPPassword string `json:"smtp_password,omitempty"`
+	SMTPHost     string `json:"smtp_host,omitempty"`
+	SMTPPort     int    `json:"smtp_port,omitempty"`
 }
 
 // ConnectResponse represents a response from Unipile
 type ConnectResponse struct {
-	AccountID   string `json:"account_id"`
-	Provider    string `json:"provider"`
-	Name        string `json:"name,omitempty"`
-	Username    string `json:"username,omitempty"`
-	Status      string `json:"status,omitempty"`
-	Message     string `json:"message,omitempty"`
-	Error       string `json:"error,omitempty"`
-	Description string `json:"description,omitempty"`
+	Object     string      `json:"object,omitempty"` // "AccountCreated" or "Checkpoint"
+	AccountID  string      `json:"account_id"`
+	Provider   string      `json:"provider"`
+	Name       string      `json:"name,omitempty"`
+	Username   string      `json:"username,omitempty"`
+	Status     any         `json:"status,omitempty"` // Can be string or number
+	Checkpoint *Checkpoint `json:"checkpoint,omitempty"`
+}
+
+// Checkpoint types a provider may require before a connection completes
+const (
+	CheckpointTwoFactor       = "2FA"
+	CheckpointOTP             = "OTP"
+	CheckpointCaptcha         = "CAPTCHA"
+	CheckpointInAppValidation = "IN_APP_VALIDATION"
+	CheckpointPhoneRegister   = "PHONE_REGISTER"
+	CheckpointQRCode          = "QRCODE" // WhatsApp and Telegram
+)
+
+// Checkpoint describes the verification step the provider is asking for
+type Checkpoint struct {
+	Type   string `json:"type"`
+	QRCode string `json:"qrcode,omitempty"` // for QRCODE checkpoints
+}
+
+// IsCheckpoint reports whether the connection is waiting on a verification step
+func (r *ConnectResponse) IsCheckpoint() bool {
+	return r.Checkpoint != nil || r.Object == "Checkpoint"
+}
+
+// CheckpointType returns the pending checkpoint type, if any
+func (r *ConnectResponse) CheckpointType() string {
+	if r.Checkpoint == nil {
+		return ""
+	}
+	return r.Checkpoint.Type
+}
+
+// CheckpointQRCode returns the QR code of a QRCODE checkpoint, if any
+func (r *ConnectResponse) CheckpointQRCode() string {
+	if r.Checkpoint == nil {
+		return ""
+	}
+	return r.Checkpoint.QRCode
+}
+
+// IsPolledCheckpoint reports whether a checkpoint is completed outside the
+// API, in the provider's app, so its account status is polled rather than
+// sent a code
+func IsPolledCheckpoint(checkpointType string) bool {
+	return checkpointType == CheckpointInAppValidation || checkpointType == CheckpointQRCode
+}
+
+// CheckpointRequest represents a request to solve a connection checkpoint
+type CheckpointRequest struct {
+	Provider  string `json:"provider"`
+	AccountID string `json:"account_id"`
+	Code      string `json:"code"`
+}
+
+// Account represents an account as returned by Unipile's GET /accounts/{id}
+type Account struct {
+	Object    string          `json:"object,omitempty"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name,omitempty"`
+	Type      string          `json:"type,omitempty"`
+	CreatedAt string          `json:"created_at,omitempty"`
+	Sources   []AccountSource `json:"sources,omitempty"`
+}
+
+// AccountSource holds the connection status of one of the account's sources
+type AccountSource struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// Account source statuses reported by Unipile
+const (
+	AccountStatusOK           = "OK"
+	AccountStatusCredentials  = "CREDENTIALS"
+	AccountStatusDisconnected = "DISCONNECTED"
+	AccountStatusConnecting   = "CONNECTING"
+	AccountStatusError        = "ERROR"
+)
+
+// Status returns the least healthy status across the account's sources
+func (a *Account) Status() string {
+	status := ""
+	for _, source := range a.Sources {
+		if source.Status == "" {
+			continue
+		}
+		if status == "" || source.Status != AccountStatusOK {
+			status = source.Status
+		}
+	}
+	return status
+}
+
+// DisplayName returns the best available name for the connected account
+func (r *ConnectResponse) DisplayName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return r.Username
+}
+
+// ErrorBody holds the error fields Unipile may return (multiple formats supported)
+type ErrorBody struct {
+	Error       string `json:"error,omitempty"`       // Generic error
+	Message     string `json:"message,omitempty"`     // Generic message
+	Description string `json:"description,omitempty"` // Error description
+
+	// Unipile specific error format
+	Type   string `json:"type,omitempty"`   // e.g., "errors/invalid_credentials"
+	Title  string `json:"title,omitempty"`  // e.g., "Invalid credentials"
+	Detail string `json:"detail,omitempty"` // e.g., "The provided credentials are invalid."
+}
+
+// ErrMaybeDelivered wraps failures of requests that aren't idempotent and
+// may have been carried out anyway, e.g. on a timeout. Sending them again
+// could send the same message or invitation twice.
+var ErrMaybeDelivered = errors.New("Unipile may have carried out the request")
+
+// APIError is returned when Unipile responds with a non-success status
+type APIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// Retryable reports whether the request may succeed if sent again
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
 }
 
 // ConnectAccount connects an account via Unipile API
-func (s *UnipileService) ConnectAccount(req ConnectRequest) (*ConnectResponse, error) {
-	if s.apiKey == "" {
-		return nil, fmt.Errorf("Unipile API key is not configured")
+func (s *UnipileService) ConnectAccount(ctx context.Context, req ConnectRequest) (*ConnectResponse, error) {
+	var unipileResp ConnectResponse
+	if _, err := s.do(ctx, http.MethodPost, "/accounts", req, &unipileResp); err != nil {
+		return nil, err
+	}
+
+	// Validate response
+	if unipileResp.AccountID == "" {
+		return nil, fmt.Errorf("invalid response from Unipile API: missing account_id")
+	}
+
+	return &unipileResp, nil
+}
+
+// ReconnectAccount refreshes the credentials of an existing Unipile account.
+// Like ConnectAccount, the response may be a checkpoint to solve.
+func (s *UnipileService) ReconnectAccount(ctx context.Context, accountID string, req ConnectRequest) (*ConnectResponse, error) {
+	var unipileResp ConnectResponse
+	if _, err := s.do(ctx, http.MethodPost, "/accounts/"+url.PathEscape(accountID), req, &unipileResp); err != nil {
+		return nil, err
+	}
+
+	if unipileResp.AccountID == "" {
+		unipileResp.AccountID = accountID
+	}
+
+	return &unipileResp, nil
+}
+
+// SolveCheckpoint forwards a verification code for a pending connection of
+// the given Unipile provider type. The response is either the created account
+// or another checkpoint to solve.
+func (s *UnipileService) SolveCheckpoint(ctx context.Context, provider, accountID, code string) (*ConnectResponse, error) {
+	req := CheckpointRequest{
+		Provider:  provider,
+		AccountID: accountID,
+		Code:      code,
+	}
+
+	var unipileResp ConnectResponse
+	if _, err := s.do(ctx, http.MethodPost, "/accounts/checkpoint", req, &unipileResp); err != nil {
+		return nil, err
+	}
+
+	if unipileResp.AccountID == "" {
+		unipileResp.AccountID = accountID
 	}
 
-	// Prepare request body
-	jsonData, err := json.Marshal(req)
+	return &unipileResp, nil
+}
+
+// HostedAuthLinkRequest is the body of POST /hosted/accounts/link. APIURL is
+// filled in from the client's configuration.
+type HostedAuthLinkRequest struct {
+	Type               string    `json:"type"` // "create" or "reconnect"
+	Providers          []string  `json:"providers"`
+	APIURL             string    `json:"api_url"`
+	ExpiresOn          time.Time `json:"expiresOn"`
+	NotifyURL          string    `json:"notify_url"`
+	Name               string    `json:"name"` // echoed back to NotifyURL
+	SuccessRedirectURL string    `json:"success_redirect_url,omitempty"`
+	FailureRedirectURL string    `json:"failure_redirect_url,omitempty"`
+}
+
+// HostedAuthLink is a link to Unipile's hosted auth wizard
+type HostedAuthLink struct {
+	Object string `json:"object"`
+	URL    string `json:"url"`
+}
+
+// CreateHostedAuthLink creates a link to Unipile's hosted auth wizard, where
+// users connect accounts without their credentials passing through us
+func (s *UnipileService) CreateHostedAuthLink(ctx context.Context, req HostedAuthLinkRequest) (*HostedAuthLink, error) {
+	// The wizard wants the instance's base URL, without the API path
+	apiURL, err := url.Parse(s.apiURL)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %v", err)
+		return nil, fmt.Errorf("config error: invalid Unipile API URL: %v", err)
+	}
+	req.APIURL = apiURL.Scheme + "://" + apiURL.Host
+
+	var link HostedAuthLink
+	if _, err := s.do(ctx, http.MethodPost, "/hosted/accounts/link", req, &link); err != nil {
+		return nil, err
+	}
+	if link.URL == "" {
+		return nil, fmt.Errorf("invalid response from Unipile API: missing url")
+	}
+	return &link, nil
+}
+
+// GetAccount retrieves an account and its connection status
+func (s *UnipileService) GetAccount(ctx context.Context, accountID string) (*Account, error) {
+	var account Account
+	if _, err := s.do(ctx, http.MethodGet, "/accounts/"+url.PathEscape(accountID), nil, &account); err != nil {
+		return nil, err
 	}
+	return &account, nil
+}
+
+// DeleteAccount disconnects and removes an account from Unipile. An account
+// that no longer exists in Unipile is treated as already deleted.
+func (s *UnipileService) DeleteAccount(ctx context.Context, accountID string) error {
+	_, err := s.do(ctx, http.MethodDelete, "/accounts/"+url.PathEscape(accountID), nil, nil)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// ConnectWithCookie connects a LinkedIn account using cookie authentication
+func (s *UnipileService) ConnectWithCookie(ctx context.Context, cookie string) (*ConnectResponse, error) {
+	return s.ConnectAccount(ctx, ConnectRequest{
+		Provider:    "LINKEDIN",
+		AccessToken: cookie, // li_at cookie value
+	})
+}
+
+// ConnectWithCredentials connects a LinkedIn account using username/password
+func (s *UnipileService) ConnectWithCredentials(ctx context.Context, username, password string) (*ConnectResponse, error) {
+	return s.ConnectAccount(ctx, ConnectRequest{
+		Provider: "LINKEDIN",
+		Username: username,
+		Password: password,
+	})
+}
+
+// ReconnectWithCookie refreshes an existing LinkedIn account with a new li_at cookie
+func (s *UnipileService) ReconnectWithCookie(ctx context.Context, accountID, cookie string) (*ConnectResponse, error) {
+	return s.ReconnectAccount(ctx, accountID, ConnectRequest{
+		Provider:    "LINKEDIN",
+		AccessToken: cookie,
+	})
+}
+
+// ReconnectWithCredentials refreshes an existing LinkedIn account with username/password
+func (s *UnipileService) ReconnectWithCredentials(ctx context.Context, accountID, username, password string) (*ConnectResponse, error) {
+	return s.ReconnectAccount(ctx, accountID, ConnectRequest{
+		Provider: "LINKEDIN",
+		Username: username,
+		Password: password,
+	})
+}
+
+// requestBody is an encoded request body, kept in memory so it can be
+// replayed on retries
+type requestBody struct {
+	data        []byte
+	contentType string
+}
+
+// do sends a JSON request to Unipile. payload may be nil for requests without
+// a body. See send for retry behavior.
+func (s *UnipileService) do(ctx context.Context, method, path string, payload, out any) (int, error) {
+	var body *requestBody
+	if payload != nil {
+		jsonData, err := json.Marshal(payload)
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal request: %v", err)
+		}
+		body = &requestBody{data: jsonData, contentType: "application/json"}
+	}
+
+	return s.send(ctx, method, path, body, out)
+}
+
+// doForm sends a multipart/form-data request to Unipile, which some endpoints
+// (such as sending messages) require. Repeated values become repeated fields.
+func (s *UnipileService) doForm(ctx context.Context, method, path string, fields url.Values, out any) (int, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for name, values := range fields {
+		for _, value := range values {
+			if err := writer.WriteField(name, value); err != nil {
+				return 0, fmt.Errorf("failed to build form: %v", err)
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return 0, fmt.Errorf("failed to build form: %v", err)
+	}
+
+	return s.send(ctx, method, path, &requestBody{data: buf.Bytes(), contentType: writer.FormDataContentType()}, out)
+}
+
+// send sends a request to Unipile, retrying transient failures (network errors,
+// 429 and 5xx) with jittered exponential backoff. Each attempt is bounded by the
+// configured timeout and the whole call by ctx. On success the JSON body is
+// decoded into out (if non-nil) and the HTTP status code is returned.
+func (s *UnipileService) send(ctx context.Context, method, path string, body *requestBody, out any) (int, error) {
+	if s.apiKey == "" {
+		slog.ErrorContext(ctx, "Unipile API key is not configured")
+		return 0, fmt.Errorf("config error: Unipile API key is not configured")
+	}
+
+	endpoint := s.apiURL + path
+
+	var status int
+	var lastErr error
+	for attempt := 0; attempt <= s.retryAttempts; attempt++ {
+		if attempt > 0 {
+			delay := s.backoff(attempt, lastErr)
+			slog.WarnContext(ctx, "Retrying Unipile request", "method", method, "endpoint", endpoint, "delay", delay.String(), "attempt", attempt+1, "max_attempts", s.retryAttempts+1, "error", lastErr)
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return 0, fmt.Errorf("failed to call Unipile API: %w", ctx.Err())
+			case <-timer.C:
+			}
+		}
+
+		status, lastErr = s.attempt(ctx, method, endpoint, body, out)
+		if lastErr == nil {
+			return status, nil
+		}
+		if !isRetryable(ctx, method, lastErr) {
+			break
+		}
+	}
+
+	if !idempotent(method) && !unsent(lastErr) {
+		return status, fmt.Errorf("%w: %w", ErrMaybeDelivered, lastErr)
+	}
+	return status, lastErr
+}
+
+// attempt performs a single HTTP round trip bounded by the configured timeout
+func (s *UnipileService) attempt(ctx context.Context, method, endpoint string, body *requestBody, out any) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
 
-	// Create HTTP request
-	url := fmt.Sprintf("%s/accounts", s.apiURL)
-	httpReq, err := http.NewRequest("POST", url, bytes.NewBuffer(jsonData))
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body.data)
+	}
+
+	if body != nil {
+		slog.DebugContext(ctx, "Unipile request body", "method", method, "url", endpoint, "body", string(body.data))
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return 0, fmt.Errorf("failed to create request: %v", err)
 	}
 
 	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	if body != nil {
+		httpReq.Header.Set("Content-Type", body.contentType)
+	}
 	httpReq.Header.Set("X-API-KEY", s.apiKey)
+	// Let Unipile support trace the call back to the request that made it
+	if id := logging.RequestID(ctx); id != "" {
+		httpReq.Header.Set(logging.RequestIDHeader, id)
+	}
 
 	// Make the request
 	resp, err := s.client.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call Unipile API: %v", err)
+		slog.ErrorContext(ctx, "Unipile request failed", "method", method, "url", endpoint, "error", err)
+		return 0, fmt.Errorf("failed to call Unipile API: %w", err)
 	}
 	defer resp.Body.Close()
 
+	slog.InfoContext(ctx, "Unipile request", "method", method, "url", endpoint, "status", resp.StatusCode)
+
 	// Read response body
-	body, err := io.ReadAll(resp.Body)
+	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %v", err)
-	}
-
-	// Parse response
-	var unipileResp ConnectResponse
-	if err := json.Unmarshal(body, &unipileResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %v", err)
+		return resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
 	}
+	slog.DebugContext(ctx, "Unipile response", "method", method, "url", endpoint, "headers", resp.Header, "body", string(respBody))
 
 	// Check for errors
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		errorMsg := unipileResp.Error
-		if errorMsg == "" {
-			errorMsg = unipileResp.Message
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var errBody ErrorBody
+		_ = json.Unmarshal(respBody, &errBody)
+		apiErr := &APIError{
+			StatusCode: resp.StatusCode,
+			Type:       errBody.Type,
+			Message:    errBody.ErrorMessage(),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
 		}
-		if errorMsg == "" {
-			errorMsg = "Unknown error from Unipile API"
+		slog.ErrorContext(ctx, "Unipile API returned error status", "status", resp.StatusCode, "message", apiErr.Message)
+		return resp.StatusCode, apiErr
+	}
+
+	// Parse response
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			slog.ErrorContext(ctx, "Failed to parse Unipile JSON response", "error", err)
+			return resp.StatusCode, fmt.Errorf("failed to parse response: %v", err)
 		}
-		return nil, fmt.Errorf("Unipile API error: %s", errorMsg)
 	}
 
-	// Validate response
-	if unipileResp.AccountID == "" {
-		return nil, fmt.Errorf("invalid response from Unipile API: missing account_id")
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the given retry attempt. It doubles the
+// configured retry delay per attempt with full jitter, capped at
+// maxUnipileRetryDelay, and waits for a server-sent Retry-After, up to that
+// cap as well.
+func (s *UnipileService) backoff(attempt int, lastErr error) time.Duration {
+	delay := s.retryDelay << (attempt - 1)
+	if delay <= 0 || delay > maxUnipileRetryDelay {
+		delay = maxUnipileRetryDelay
 	}
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
 
-	return &unipileResp, nil
+	var apiErr *APIError
+	if errors.As(lastErr, &apiErr) && apiErr.RetryAfter > delay {
+		delay = min(apiErr.RetryAfter, maxUnipileRetryDelay)
+	}
+	return delay
 }
 
-// ConnectWithCookie connects a LinkedIn account using cookie authentication
-func (s *UnipileService) ConnectWithCookie(cookie string) (accountID, accountName string, err error) {
-	req := ConnectRequest{
-		Provider: "linkedin",
-		Cookie:   cookie,
-		Type:     "cookie",
+// isRetryable reports whether err is a transient failure worth retrying.
+// Requests that aren't idempotent, such as sending a message, are only
+// retried when they never reached Unipile, so they can't act twice.
+func isRetryable(ctx context.Context, method string, err error) bool {
+	// The caller gave up; retrying cannot help
+	if ctx.Err() != nil {
+		return false
+	}
+	if !idempotent(method) && !unsent(err) {
+		return false
 	}
 
-	resp, err := s.ConnectAccount(req)
-	if err != nil {
-		return "", "", err
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Retryable()
 	}
 
-	name := resp.Name
-	if name == "" {
-		name = resp.Username
+	// Network errors and per-attempt timeouts
+	var netErr interface{ Timeout() bool }
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// idempotent reports whether sending a request twice has the same effect as
+// sending it once
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
 	}
+	return false
+}
 
-	return resp.AccountID, name, nil
+// unsent reports whether a request provably wasn't carried out: the
+// connection was never made, or Unipile rejected it without a server error
+func unsent(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode < http.StatusInternalServerError
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
 }
 
-// ConnectWithCredentials connects a LinkedIn account using username/password
-func (s *UnipileService) ConnectWithCredentials(username, password string) (accountID, accountName string, err error) {
-	req := ConnectRequest{
-		Provider: "linkedin",
-		Username: username,
-		Password: password,
-		Type:     "credentials",
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
 	}
+	return 0
+}
 
-	resp, err := s.ConnectAccount(req)
-	if err != nil {
-		return "", "", err
+// ErrorMessage extracts the best error message from a Unipile error response
+func (r *ErrorBody) ErrorMessage() string {
+	// Priority order for error messages:
+	// 1. Detail (most descriptive)
+	// 2. Title (error title)
+	// 3. Error (generic error field)
+	// 4. Description (alternative description)
+	// 5. Message (generic message)
+	// 6. Type (error type)
+
+	if r.Detail != "" {
+		// If we have both title and detail, combine them
+		if r.Title != "" {
+			return fmt.Sprintf("%s: %s", r.Title, r.Detail)
+		}
+		return r.Detail
+	}
+
+	if r.Title != "" {
+		return r.Title
+	}
+
+	if r.Error != "" {
+		if r.Description != "" {
+			return fmt.Sprintf("%s: %s", r.Error, r.Description)
+		}
+		return r.Error
+	}
+
+	if r.Description != "" {
+		return r.Description
+	}
+
+	if r.Message != "" {
+		return r.Message
 	}
 
-	name := resp.Name
-	if name == "" {
-		name = resp.Username
+	if r.Type != "" {
+		return fmt.Sprintf("Error type: %s", r.Type)
 	}
 
-	return resp.AccountID, name, nil
+	return "Unknown error from Unipile API"
 }
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		err    error
+		want   bool
+	}{
+		{"GET server error", nil, http.MethodGet, &APIError{StatusCode: 500}, true},
+		{"GET rate limited", nil, http.MethodGet, &APIError{StatusCode: 429}, true},
+		{"GET client error", nil, http.MethodGet, &APIError{StatusCode: 404}, false},
+		{"GET timeout", nil, http.MethodGet, fmt.Errorf("request: %w", context.DeadlineExceeded), true},
+		{"GET unexpected EOF", nil, http.MethodGet, io.ErrUnexpectedEOF, true},
+		{"DELETE connection reset", nil, http.MethodDelete, readErr, true},
+		{"POST server error", nil, http.MethodPost, &APIError{StatusCode: 502}, false},
+		{"POST rate limited", nil, http.MethodPost, &APIError{StatusCode: 429}, true},
+		{"POST client error", nil, http.MethodPost, &APIError{StatusCode: 400}, false},
+		{"POST connection refused", nil, http.MethodPost, fmt.Errorf("send: %w", dialErr), true},
+		{"POST connection reset", nil, http.MethodPost, readErr, false},
+		{"POST timeout", nil, http.MethodPost, context.DeadlineExceeded, false},
+		{"unknown error", nil, http.MethodGet, errors.New("boom"), false},
+		{"caller cancelled", cancelled, http.MethodGet, &APIError{StatusCode: 503}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if got := isRetryable(ctx, tt.method, tt.err); got != tt.want {
+				t.Errorf("isRetryable(%s, %v) = %v, want %v", tt.method, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnipileServiceBackoff(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryDelay time.Duration
+		attempt    int
+		err        error
+		min, max   time.Duration
+	}{
+		{"first attempt", time.Second, 1, nil, 500 * time.Millisecond, time.Second},
+		{"doubles per attempt", time.Second, 3, nil, 2 * time.Second, 4 * time.Second},
+		{"capped", time.Second, 10, nil, maxUnipileRetryDelay / 2, maxUnipileRetryDelay},
+		{"overflow is capped", time.Second, 70, nil, maxUnipileRetryDelay / 2, maxUnipileRetryDelay},
+		{"longer Retry-After wins", time.Second, 1, &APIError{StatusCode: 429, RetryAfter: 5 * time.Second}, 5 * time.Second, 5 * time.Second},
+		{"Retry-After is capped", time.Second, 1, &APIError{StatusCode: 429, RetryAfter: time.Hour}, maxUnipileRetryDelay, maxUnipileRetryDelay},
+		{"shorter Retry-After is ignored", 4 * time.Second, 1, &APIError{StatusCode: 429, RetryAfter: time.Second}, 2 * time.Second, 4 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &UnipileService{retryDelay: tt.retryDelay}
+			for i := 0; i < 100; i++ {
+				if got := s.backoff(tt.attempt, tt.err); got < tt.min || got > tt.max {
+					t.Fatalf("backoff(%d) = %v, want between %v and %v", tt.attempt, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value    string
+		min, max time.Duration
+	}{
+		{"", 0, 0},
+		{"7", 7 * time.Second, 7 * time.Second},
+		{"-1", 0, 0},
+		{"soon", 0, 0},
+		{time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), 58 * time.Second, time.Minute},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value); got < tt.min || got > tt.max {
+			t.Errorf("parseRetryAfter(%q) = %v, want between %v and %v", tt.value, got, tt.min, tt.max)
+		}
+	}
+}
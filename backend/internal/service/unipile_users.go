@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// UserProfile is a LinkedIn member profile as returned by Unipile's
+// GET /users/{identifier}
+type UserProfile struct {
+	Object           string `json:"object"`
+	ProviderID       string `json:"provider_id"`
+	PublicIdentifier string `json:"public_identifier"`
+	FirstName        string `json:"first_name"`
+	LastName         string `json:"last_name"`
+	Headline         string `json:"headline"`
+	Location         string `json:"location"`
+
+	// Only included when Unipile returns the experience section
+	WorkExperience []WorkExperience `json:"work_experience,omitempty"`
+}
+
+// OwnProfile is the profile of the LinkedIn member who connected an account,
+// as returned by Unipile's GET /users/me
+type OwnProfile struct {
+	Object            string `json:"object"`
+	ProviderID        string `json:"provider_id"`
+	PublicIdentifier  string `json:"public_identifier"`
+	PublicProfileURL  string `json:"public_profile_url"`
+	FirstName         string `json:"first_name"`
+	LastName          string `json:"last_name"`
+	Occupation        string `json:"occupation"` // the profile headline
+	ProfilePictureURL string `json:"profile_picture_url"`
+	Premium           bool   `json:"premium"`
+
+	// Set only when the account has a Sales Navigator seat
+	SalesNavigator *struct {
+		OwnerSeatID string `json:"owner_seat_id"`
+		ContractID  string `json:"contract_id"`
+	} `json:"sales_navigator"`
+}
+
+// WorkExperience is a position on a LinkedIn profile, most recent first
+type WorkExperience struct {
+	Company  string `json:"company"`
+	Position string `json:"position"`
+	Location string `json:"location,omitempty"`
+	Start    string `json:"start,omitempty"`
+	End      string `json:"end,omitempty"` // empty for current positions
+}
+
+// InvitationSent is Unipile's response to sending a connection request
+type InvitationSent struct {
+	Object       string `json:"object"`
+	InvitationID string `json:"invitation_id"`
+}
+
+// Relation is a first-degree connection of an account, as listed by
+// Unipile's GET /users/relations
+type Relation struct {
+	Object           string `json:"object"`
+	MemberID         string `json:"member_id"` // the member's provider ID
+	PublicIdentifier string `json:"public_identifier"`
+	FirstName        string `json:"first_name"`
+	LastName         string `json:"last_name"`
+	CreatedAt        int64  `json:"created_at"` // when they connected, in Unix milliseconds
+}
+
+// RelationList is a page of relations, most recent first. Cursor is empty on
+// the last page.
+type RelationList struct {
+	Object string     `json:"object"`
+	Items  []Relation `json:"items"`
+	Cursor string     `json:"cursor"`
+}
+
+// inviteRequest is the body of POST /users/invite
+type inviteRequest struct {
+	AccountID  string `json:"account_id"`
+	ProviderID string `json:"provider_id"`
+	Message    string `json:"message,omitempty"`
+}
+
+// GetProfile retrieves a LinkedIn profile by provider ID or public identifier,
+// as seen by the given account, with its experience section
+func (s *UnipileService) GetProfile(ctx context.Context, accountID, identifier string) (*UserProfile, error) {
+	query := url.Values{"account_id": {accountID}, "linkedin_sections": {"experience"}}
+
+	var profile UserProfile
+	path := "/users/" + url.PathEscape(identifier) + "?" + query.Encode()
+	if _, err := s.do(ctx, http.MethodGet, path, nil, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// GetOwnProfile retrieves the profile of the member who connected the account
+func (s *UnipileService) GetOwnProfile(ctx context.Context, accountID string) (*OwnProfile, error) {
+	query := url.Values{"account_id": {accountID}}
+
+	var profile OwnProfile
+	if _, err := s.do(ctx, http.MethodGet, "/users/me?"+query.Encode(), nil, &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// SendInvitation sends a connection request, with an optional note, to the
+// member with the given provider ID
+func (s *UnipileService) SendInvitation(ctx context.Context, accountID, providerID, note string) (*InvitationSent, error) {
+	req := inviteRequest{
+		AccountID:  accountID,
+		ProviderID: providerID,
+		Message:    note,
+	}
+
+	var sent InvitationSent
+	if _, err := s.do(ctx, http.MethodPost, "/users/invite", req, &sent); err != nil {
+		return nil, err
+	}
+	return &sent, nil
+}
+
+// CancelInvitation withdraws a pending connection request
+func (s *UnipileService) CancelInvitation(ctx context.Context, accountID, invitationID string) error {
+	query := url.Values{"account_id": {accountID}}
+	path := "/users/invite/sent/" + url.PathEscape(invitationID) + "?" + query.Encode()
+	_, err := s.do(ctx, http.MethodDelete, path, nil, nil)
+	return err
+}
+
+// ListRelations lists the connections of an account, most recent first
+func (s *UnipileService) ListRelations(ctx context.Context, accountID, cursor string, limit int) (*RelationList, error) {
+	query := url.Values{"account_id": {accountID}}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+
+	var relations RelationList
+	if _, err := s.do(ctx, http.MethodGet, "/users/relations?"+query.Encode(), nil, &relations); err != nil {
+		return nil, err
+	}
+	return &relations, nil
+}
+
+// ParseProfileIdentifier extracts the public identifier from a LinkedIn
+// profile URL such as https://www.linkedin.com/in/jane-doe-123/. Input that
+// isn't a profile URL is returned trimmed, so provider IDs pass through.
+func ParseProfileIdentifier(input string) (string, bool) {
+	input = strings.TrimSpace(input)
+	if !strings.Contains(input, "linkedin.com/") {
+		// Bare identifiers have no slashes; anything else is a foreign URL
+		return input, input != "" && !strings.Contains(input, "/")
+	}
+
+	if !strings.Contains(input, "://") {
+		input = "https://" + input
+	}
+	u, err := url.Parse(input)
+	if err != nil {
+		return "", false
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "in" {
+		return "", false
+	}
+
+	identifier, err := url.PathUnescape(parts[1])
+	if err != nil || identifier == "" {
+		return "", false
+	}
+	return identifier, true
+}
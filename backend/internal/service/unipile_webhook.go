@@ -0,0 +1,135 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+)
+
+// webhookDedupWindow is how long an identical webhook without an ID is
+// treated as a redelivery
+const webhookDedupWindow = 10 * time.Minute
+
+// Webhook event types stored on models.WebhookEvent
+const (
+	WebhookTypeAccountStatus = "account_status"
+)
+
+// WebhookPayload covers both Unipile webhook shapes: account status webhooks
+// wrap their fields in an AccountStatus object, messaging webhooks are flat
+// and carry an event name such as "message_received".
+type WebhookPayload struct {
+	AccountStatus *AccountStatusEvent `json:"AccountStatus,omitempty"`
+
+	EventID     string         `json:"event_id,omitempty"`
+	Event       string         `json:"event,omitempty"`
+	AccountID   string         `json:"account_id,omitempty"`
+	AccountType string         `json:"account_type,omitempty"`
+	ChatID      string         `json:"chat_id,omitempty"`
+	MessageID   string         `json:"message_id,omitempty"`
+	Message     string         `json:"message,omitempty"`
+	Sender      *WebhookSender `json:"sender,omitempty"`
+	AccountInfo *WebhookOwner  `json:"account_info,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+
+	// Set on new_relation events
+	UserProviderID string `json:"user_provider_id,omitempty"`
+	UserFullName   string `json:"user_full_name,omitempty"`
+}
+
+// AccountStatusEvent is the body of an account status webhook
+type AccountStatusEvent struct {
+	AccountID   string `json:"account_id"`
+	AccountType string `json:"account_type"`
+	Message     string `json:"message"` // OK, CREDENTIALS, ERROR, STOPPED, CONNECTING, ...
+}
+
+// WebhookSender identifies who sent a message in a messaging webhook
+type WebhookSender struct {
+	AttendeeID         string `json:"attendee_id,omitempty"`
+	AttendeeName       string `json:"attendee_name,omitempty"`
+	AttendeeProviderID string `json:"attendee_provider_id,omitempty"`
+}
+
+// WebhookOwner describes the connected account a messaging webhook belongs to
+type WebhookOwner struct {
+	Type   string `json:"type,omitempty"`
+	UserID string `json:"user_id,omitempty"` // provider ID of the account owner
+}
+
+// Webhook messaging events that carry a message to store
+const (
+	WebhookEventMessageReceived = "message_received"
+	WebhookEventMessageEdited   = "message_edited"
+)
+
+// WebhookEventNewRelation is sent when a member accepts a connection request
+const WebhookEventNewRelation = "new_relation"
+
+// ParseWebhook decodes a raw Unipile webhook body
+func ParseWebhook(body []byte) (*WebhookPayload, error) {
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+// Type returns the event type stored for this webhook
+func (p *WebhookPayload) Type() string {
+	if p.AccountStatus != nil {
+		return WebhookTypeAccountStatus
+	}
+	return p.Event
+}
+
+// UnipileAccountID returns the Unipile account the webhook is about
+func (p *WebhookPayload) UnipileAccountID() string {
+	if p.AccountStatus != nil {
+		return p.AccountStatus.AccountID
+	}
+	return p.AccountID
+}
+
+// ID returns a stable identifier for deduplicating redelivered webhooks.
+// Unipile doesn't send one for every event type, so new messages fall back to
+// the message ID, and other events about a message, such as edits and
+// reactions, which may happen more than once, to the message ID and a hash of
+// the raw body. Anything else uses the hash within webhookDedupWindow, so a
+// status that recurs later is not mistaken for a redelivery.
+func (p *WebhookPayload) ID(body []byte, receivedAt time.Time) string {
+	if p.EventID != "" {
+		return p.EventID
+	}
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+	switch {
+	case p.MessageID != "" && p.Event == WebhookEventMessageReceived:
+		return p.Event + ":" + p.MessageID
+	case p.MessageID != "":
+		return p.Event + ":" + p.MessageID + ":" + hash
+	}
+	window := receivedAt.Truncate(webhookDedupWindow).Unix()
+	return fmt.Sprintf("sha256:%s:%d", hash, window)
+}
+
+// ToSynced converts a messaging webhook into a row of the local message store
+func (p *WebhookPayload) ToSynced(linkedAccountID uint) models.SyncedMessage {
+	message := models.SyncedMessage{
+		LinkedAccountID: linkedAccountID,
+		MessageID:       p.MessageID,
+		ChatID:          p.ChatID,
+		Text:            p.Message,
+		SentAt:          parseTimestamp(p.Timestamp),
+	}
+	if p.Sender != nil {
+		message.SenderID = p.Sender.AttendeeProviderID
+		message.SenderName = p.Sender.AttendeeName
+		message.IsMe = p.AccountInfo != nil && p.AccountInfo.UserID != "" && p.AccountInfo.UserID == p.Sender.AttendeeProviderID
+	}
+	return message
+}
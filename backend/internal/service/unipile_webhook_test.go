@@ -0,0 +1,93 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookPayloadID(t *testing.T) {
+	receivedAt := time.Date(2024, 5, 1, 12, 3, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		a, b string
+		// same is whether the two webhooks count as one delivery
+		same bool
+		// later receives b outside the dedup window
+		later bool
+	}{
+		{
+			name: "event ID",
+			a:    `{"event_id":"evt_1","event":"message_received","message_id":"m1"}`,
+			b:    `{"event_id":"evt_1","event":"message_received","message_id":"m1","message":"retry"}`,
+			same: true,
+		},
+		{
+			name: "redelivered message",
+			a:    `{"event":"message_received","message_id":"m1","message":"Hi"}`,
+			b:    `{"event":"message_received","message_id":"m1","message":"Hi","timestamp":"2024-05-01T12:00:00Z"}`,
+			same: true,
+		},
+		{
+			name: "redelivered edit",
+			a:    `{"event":"message_edited","message_id":"m1","message":"Hi!"}`,
+			b:    `{"event":"message_edited","message_id":"m1","message":"Hi!"}`,
+			same: true,
+		},
+		{
+			name: "second edit of a message",
+			a:    `{"event":"message_edited","message_id":"m1","message":"Hi!"}`,
+			b:    `{"event":"message_edited","message_id":"m1","message":"Hi!!"}`,
+		},
+		{
+			name: "reactions to a message",
+			a:    `{"event":"message_reaction","message_id":"m1","reaction":"👍"}`,
+			b:    `{"event":"message_reaction","message_id":"m1","reaction":"🎉"}`,
+		},
+		{
+			name: "edit of a received message",
+			a:    `{"event":"message_received","message_id":"m1","message":"Hi"}`,
+			b:    `{"event":"message_edited","message_id":"m1","message":"Hi"}`,
+		},
+		{
+			name: "redelivered status",
+			a:    `{"AccountStatus":{"account_id":"acc","message":"OK"}}`,
+			b:    `{"AccountStatus":{"account_id":"acc","message":"OK"}}`,
+			same: true,
+		},
+		{
+			name:  "recurring status",
+			a:     `{"AccountStatus":{"account_id":"acc","message":"OK"}}`,
+			b:     `{"AccountStatus":{"account_id":"acc","message":"OK"}}`,
+			later: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idA := webhookID(t, tt.a, receivedAt)
+			receivedB := receivedAt
+			if tt.later {
+				receivedB = receivedB.Add(webhookDedupWindow)
+			}
+			idB := webhookID(t, tt.b, receivedB)
+			if (idA == idB) != tt.same {
+				t.Errorf("IDs %q and %q, want same = %v", idA, idB, tt.same)
+			}
+		})
+	}
+}
+
+func webhookID(t *testing.T, body string, receivedAt time.Time) string {
+	t.Helper()
+	payload, err := ParseWebhook([]byte(body))
+	if err != nil {
+		t.Fatalf("parse %s: %v", body, err)
+	}
+	id := payload.ID([]byte(body), receivedAt)
+	if strings.TrimSpace(id) == "" {
+		t.Fatalf("empty ID for %s", body)
+	}
+	return id
+}
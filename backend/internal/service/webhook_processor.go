@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+	"github.com/johnson7543/chatsheet-assessment/internal/repository"
+)
+
+const (
+	// webhookQueueSize bounds how many stored events may wait for processing
+	// in memory; events that don't fit are picked up by the next poll
+	webhookQueueSize = 256
+
+	// webhookPollInterval is how often unprocessed events are looked for in
+	// the database
+	webhookPollInterval = time.Minute
+
+	// maxWebhookAttempts is how many times an event failing on transient
+	// errors is tried before it's given up on
+	maxWebhookAttempts = 5
+)
+
+// WebhookProcessor applies stored Unipile webhook events asynchronously so the
+// webhook endpoint can acknowledge deliveries immediately
+type WebhookProcessor struct {
+	events      *repository.WebhookEventRepository
+	accounts    *repository.LinkedAccountRepository
+	messages    *repository.MessageRepository
+	invitations *repository.InvitationRepository
+	queue       chan uint
+}
+
+// NewWebhookProcessor creates a new webhook processor
+func NewWebhookProcessor(events *repository.WebhookEventRepository, accounts *repository.LinkedAccountRepository, messages *repository.MessageRepository, invitations *repository.InvitationRepository) *WebhookProcessor {
+	return &WebhookProcessor{
+		events:      events,
+		accounts:    accounts,
+		messages:    messages,
+		invitations: invitations,
+		queue:       make(chan uint, webhookQueueSize),
+	}
+}
+
+// Start processes events left over from a previous run, then handles queued
+// events until ctx is done. Every webhookPollInterval it also processes the
+// events still unprocessed: those that didn't fit in the queue and those
+// that failed on a transient error.
+func (p *WebhookProcessor) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(webhookPollInterval)
+		defer ticker.Stop()
+
+		p.processBacklog()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.processBacklog()
+			case id := <-p.queue:
+				event, err := p.events.FindByID(id)
+				if err != nil {
+					slog.Error("Failed to load webhook event", "event_id", id, "error", err)
+					continue
+				}
+				p.process(event)
+			}
+		}
+	}()
+}
+
+// Enqueue schedules a stored event for processing without blocking
+func (p *WebhookProcessor) Enqueue(eventID uint) {
+	select {
+	case p.queue <- eventID:
+	default:
+		slog.Warn("Webhook queue full, event will be processed by the next poll", "event_id", eventID)
+	}
+}
+
+// processBacklog handles events stored but never processed
+func (p *WebhookProcessor) processBacklog() {
+	events, err := p.events.FindUnprocessed(1000)
+	if err != nil {
+		slog.Error("Failed to load unprocessed webhook events", "error", err)
+		return
+	}
+	for i := range events {
+		p.process(&events[i])
+	}
+}
+
+// process applies one event and marks it processed. Events failing on a
+// database error are left unprocessed, to be retried by the next poll.
+func (p *WebhookProcessor) process(event *models.WebhookEvent) {
+	if event.ProcessedAt != nil {
+		return
+	}
+
+	payload, err := ParseWebhook([]byte(event.Payload))
+	if err != nil {
+		p.markProcessed(event, nil, err.Error())
+		return
+	}
+
+	accounts, err := p.accounts.FindByAccountID(event.AccountID)
+	if err != nil {
+		p.retryLater(event, nil, err)
+		return
+	}
+	if len(accounts) == 0 {
+		p.markProcessed(event, nil, "no linked account for "+event.AccountID)
+		return
+	}
+	linkedAccountID := &accounts[0].ID
+
+	switch event.Type {
+	case WebhookTypeAccountStatus:
+		err = p.applyAccountStatus(payload.AccountStatus, accounts)
+	case WebhookEventMessageReceived, WebhookEventMessageEdited:
+		err = p.storeMessage(payload, accounts)
+	case WebhookEventNewRelation:
+		err = p.acceptInvitation(payload, accounts)
+	default:
+		// Other messaging events are kept in webhook_events for later use
+	}
+
+	if err != nil {
+		p.retryLater(event, linkedAccountID, err)
+		return
+	}
+	p.markProcessed(event, linkedAccountID, "")
+}
+
+// applyAccountStatus stores the status reported by an account status webhook
+func (p *WebhookProcessor) applyAccountStatus(status *AccountStatusEvent, accounts []models.LinkedAccount) error {
+	newStatus := webhookAccountStatus(status.Message)
+	lastError := ""
+	if newStatus == models.AccountStatusError {
+		lastError = "Unipile reported " + status.Message
+	}
+
+	for _, account := range accounts {
+		// Don't resurrect accounts waiting to be deleted
+		updated, err := p.accounts.UpdateStatusUnlessPendingDeletion(account.ID, newStatus, lastError, time.Now())
+		if err != nil {
+			return err
+		}
+		if updated && account.Status != newStatus {
+			slog.Info("Account status changed via webhook", "account_id", account.ID, "from", account.Status, "to", newStatus)
+		}
+	}
+	return nil
+}
+
+// storeMessage adds the message of a messaging webhook to the local store of
+// every linked account it belongs to
+func (p *WebhookProcessor) storeMessage(payload *WebhookPayload, accounts []models.LinkedAccount) error {
+	if payload.MessageID == "" || payload.ChatID == "" {
+		return nil
+	}
+
+	messages := make([]models.SyncedMessage, 0, len(accounts))
+	for _, account := range accounts {
+		messages = append(messages, payload.ToSynced(account.ID))
+	}
+	return p.messages.UpsertMessages(messages)
+}
+
+// acceptInvitation marks pending invitations to the new relation as accepted
+func (p *WebhookProcessor) acceptInvitation(payload *WebhookPayload, accounts []models.LinkedAccount) error {
+	if payload.UserProviderID == "" {
+		return nil
+	}
+
+	for _, account := range accounts {
+		accepted, err := p.invitations.MarkAccepted(account.ID, payload.UserProviderID, time.Now())
+		if err != nil {
+			return err
+		}
+		if accepted > 0 {
+			slog.Info("Invitation accepted", "account_id", account.ID, "provider_id", payload.UserProviderID)
+		}
+	}
+	return nil
+}
+
+// retryLater leaves an event that failed on a transient error for the next
+// poll, giving up on it after maxWebhookAttempts
+func (p *WebhookProcessor) retryLater(event *models.WebhookEvent, linkedAccountID *uint, err error) {
+	if event.Attempts+1 >= maxWebhookAttempts {
+		p.markProcessed(event, linkedAccountID, err.Error())
+		return
+	}
+
+	slog.Warn("Webhook event failed, will retry", "unipile_event_id", event.EventID, "type", event.Type, "attempt", event.Attempts+1, "error", err)
+	if err := p.events.RecordFailure(event, err.Error()); err != nil {
+		slog.Error("Failed to record webhook event failure", "event_id", event.ID, "error", err)
+	}
+}
+
+func (p *WebhookProcessor) markProcessed(event *models.WebhookEvent, linkedAccountID *uint, errMsg string) {
+	if errMsg != "" {
+		slog.Warn("Webhook event not applied", "unipile_event_id", event.EventID, "type", event.Type, "reason", errMsg)
+	}
+	if err := p.events.MarkProcessed(event, linkedAccountID, errMsg); err != nil {
+		slog.Error("Failed to mark webhook event processed", "event_id", event.ID, "error", err)
+	}
+}
+
+// webhookAccountStatus maps the message of an account status webhook onto a
+// LinkedAccount status
+func webhookAccountStatus(message string) string {
+	if message == "STOPPED" {
+		return models.AccountStatusDisconnected
+	}
+	return accountStatus(message)
+}
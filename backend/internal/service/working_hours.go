@@ -0,0 +1,106 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/johnson7543/chatsheet-assessment/internal/models"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// WorkingHours is the weekly window in which queued actions of an account may
+// run. A window ending before it starts spans midnight.
+type WorkingHours struct {
+	Location *time.Location
+	Start    int // minutes after midnight
+	End      int
+	Days     [7]bool
+}
+
+// ParseWorkingHours validates a timezone, "HH:MM" start and end times and
+// weekday names (Mon, Tuesday, ...)
+func ParseWorkingHours(timezone, start, end string, days []string) (*WorkingHours, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q", timezone)
+	}
+
+	wh := &WorkingHours{Location: location}
+	if wh.Start, err = parseClock(start); err != nil {
+		return nil, err
+	}
+	if wh.End, err = parseClock(end); err != nil {
+		return nil, err
+	}
+	if wh.Start == wh.End {
+		return nil, fmt.Errorf("working hours must not start and end at the same time")
+	}
+
+	for _, day := range days {
+		name := strings.ToLower(strings.TrimSpace(day))
+		if len(name) > 3 {
+			name = name[:3]
+		}
+		weekday, ok := weekdays[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid working day %q", day)
+		}
+		wh.Days[weekday] = true
+	}
+	return wh, nil
+}
+
+// AccountWorkingHours returns the working hours configured on a linked account
+func AccountWorkingHours(account *models.LinkedAccount) (*WorkingHours, error) {
+	return ParseWorkingHours(account.Timezone, account.WorkingHoursStart, account.WorkingHoursEnd,
+		strings.Split(account.WorkingDays, ","))
+}
+
+// Contains reports whether t falls within the working hours
+func (w *WorkingHours) Contains(t time.Time) bool {
+	local := t.In(w.Location)
+	minute := local.Hour()*60 + local.Minute()
+
+	if w.Start < w.End {
+		return w.Days[local.Weekday()] && minute >= w.Start && minute < w.End
+	}
+	// Overnight windows belong to the day they start on
+	if minute >= w.Start {
+		return w.Days[local.Weekday()]
+	}
+	return minute < w.End && w.Days[local.AddDate(0, 0, -1).Weekday()]
+}
+
+// FormatDays turns working days back into the comma-separated form stored on
+// LinkedAccount
+func (w *WorkingHours) FormatDays() string {
+	var names []string
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if w.Days[day] {
+			names = append(names, day.String()[:3])
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// StartClock and EndClock format the window bounds as "HH:MM"
+func (w *WorkingHours) StartClock() string { return formatClock(w.Start) }
+func (w *WorkingHours) EndClock() string   { return formatClock(w.End) }
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// parseClock parses "HH:MM" into minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -15,9 +15,9 @@ PROJECT_ROOT="$(dirname "$SCRIPT_DIR")"
 
 cd "$PROJECT_ROOT"
 
-# Build the application
+# Build the application (sqlite_fts5 enables full-text message search)
 echo "📦 Compiling Go binary..."
-go build -o bin/api -ldflags="-s -w" cmd/api/main.go
+go build -tags sqlite_fts5 -o bin/api -ldflags="-s -w" cmd/api/main.go
 
 # Check if build was successful
 if [ -f "bin/api" ]; then
@@ -37,5 +37,5 @@ echo "🎯 Running API server..."
 echo "📍 Port: ${PORT:-8080}"
 echo ""
 
-go run cmd/api/main.go
+go run -tags sqlite_fts5 cmd/api/main.go
 
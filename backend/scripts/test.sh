@@ -14,12 +14,12 @@ cd "$PROJECT_ROOT"
 
 # Run tests with coverage
 echo "📊 Running tests with coverage..."
-go test -v -cover ./...
+go test -tags sqlite_fts5 -v -cover ./...
 
 # Generate coverage report
 echo ""
 echo "📈 Generating coverage report..."
-go test -coverprofile=coverage.out ./...
+go test -tags sqlite_fts5 -coverprofile=coverage.out ./...
 
 if [ -f "coverage.out" ]; then
     echo "✅ Coverage report generated: coverage.out"
@@ -19,7 +19,7 @@ function LoginForm({ onLogin, isRegister = false }) {
         ? await register(email, password)
         : await login(email, password);
 
-      onLogin(response.data.token, response.data.user);
+      onLogin(response.data.token, response.data.user, response.data.refresh_token);
       navigate('/');
     } catch (err) {
       setError(err.response?.data?.error || 'An error occurred. Please try again.');
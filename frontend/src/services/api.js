@@ -23,12 +23,45 @@ api.interceptors.request.use(
   }
 );
 
-// Handle 401 errors by clearing token
+// The refresh in flight. Concurrent 401s share it: refresh tokens work once,
+// and a second refresh with the same token would log the user out.
+let refreshing = null;
+
+const refreshTokens = () => {
+  if (!refreshing) {
+    refreshing = axios
+      .post(`${API_URL}/api/auth/refresh`, { refresh_token: localStorage.getItem('refreshToken') })
+      .then((response) => {
+        localStorage.setItem('token', response.data.token);
+        localStorage.setItem('refreshToken', response.data.refresh_token);
+        return response.data.token;
+      })
+      .finally(() => {
+        refreshing = null;
+      });
+  }
+  return refreshing;
+};
+
+// Handle 401 errors by refreshing the access token and retrying once, then
+// by clearing the session
 api.interceptors.response.use(
   (response) => response,
-  (error) => {
+  async (error) => {
+    const request = error.config;
     if (error.response?.status === 401) {
+      if (!request._retried && !request.url.startsWith('/api/auth/') && localStorage.getItem('refreshToken')) {
+        request._retried = true;
+        try {
+          const token = await refreshTokens();
+          request.headers.Authorization = `Bearer ${token}`;
+          return api(request);
+        } catch {
+          // The refresh token expired or was revoked
+        }
+      }
       localStorage.removeItem('token');
+      localStorage.removeItem('refreshToken');
       localStorage.removeItem('user');
       window.location.href = '/login';
     }
@@ -45,6 +78,10 @@ export const login = (email, password) => {
   return api.post('/api/auth/login', { email, password });
 };
 
+export const logout = (refreshToken) => {
+  return api.post('/api/auth/logout', { refresh_token: refreshToken });
+};
+
 // LinkedIn Connection APIs
 export const connectLinkedInWithCookie = (cookie) => {
   return api.post('/api/linkedin/connect/cookie', { cookie });